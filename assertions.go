@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ---- Assertions ----
+
+// bodyAssertionKeys lists the config keys whose checks need the response
+// body. They are rejected for HEAD requests.
+var bodyAssertionKeys = []string{"expectedSha256", "jsonPath", "jq", "xmlPath", "expectedBodyAnyOf", "responseSchema", "minBodyBytes", "maxBodyBytes", "bodyMatch", "sse", "followPagination", "failOnEmptyBody", "trailerMatch"}
+
+// Values accepted by the 'matchLogic' key.
+const (
+	matchLogicAll = "all"
+	matchLogicAny = "any"
+)
+
+func parseMatchLogic(config map[string]string) (string, error) {
+	switch logic := config["matchLogic"]; logic {
+	case "":
+		return matchLogicAll, nil
+	case matchLogicAll, matchLogicAny:
+		return logic, nil
+	default:
+		return "", fmt.Errorf("invalid 'matchLogic' %q: expected all or any", logic)
+	}
+}
+
+// criterion is the outcome of a single success check.
+type criterion struct {
+	name   string
+	passed bool
+	detail string
+}
+
+// criteria holds every check evaluated for a response.
+type criteria []criterion
+
+// passed combines the individual results: with "all" every criterion must
+// pass, with "any" a single passing criterion is enough.
+func (c criteria) passed(logic string) bool {
+	for _, cr := range c {
+		if logic == matchLogicAny && cr.passed {
+			return true
+		}
+		if logic == matchLogicAll && !cr.passed {
+			return false
+		}
+	}
+	return logic == matchLogicAll
+}
+
+// describe lists each criterion with its outcome.
+func (c criteria) describe(logic string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Criteria (%s):", logic)
+	for _, cr := range c {
+		outcome := "PASS"
+		if !cr.passed {
+			outcome = "FAIL"
+		}
+		fmt.Fprintf(&b, "\n  [%s] %s", outcome, cr.name)
+		if cr.detail != "" {
+			fmt.Fprintf(&b, ": %s", cr.detail)
+		}
+	}
+	return b.String()
+}
+
+// statusCriterion checks for a 2xx status, or a 304 when acceptNotModified.
+func statusCriterion(resp *http.Response, acceptNotModified bool) criterion {
+	return criterion{
+		name: "status",
+		passed: resp.StatusCode >= 200 && resp.StatusCode < 300 ||
+			resp.StatusCode == http.StatusNotModified && acceptNotModified,
+		detail: resp.Status,
+	}
+}
+
+// expectConnectionError is the 'expectFailure' entry for a request that
+// gets no response at all.
+const expectConnectionError = "connectionError"
+
+// expectedFailure holds the 'expectFailure' outcomes that pass a negative
+// check: a connection error and/or the listed statuses.
+type expectedFailure struct {
+	connectionError bool
+	statuses        map[int]bool
+}
+
+func parseExpectFailure(config map[string]string) (*expectedFailure, error) {
+	entries := configList(config, "expectFailure")
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	if _, ok := config["celExpression"]; ok {
+		return nil, fmt.Errorf("'expectFailure' cannot be combined with 'celExpression'")
+	}
+	f := &expectedFailure{statuses: make(map[int]bool)}
+	for _, entry := range entries {
+		if entry == expectConnectionError {
+			f.connectionError = true
+			continue
+		}
+		status, err := strconv.Atoi(entry)
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid 'expectFailure' entry %q: expected %s or a status code", entry, expectConnectionError)
+		}
+		f.statuses[status] = true
+	}
+	return f, nil
+}
+
+// criterion replaces the status check: only the expected statuses pass.
+func (f *expectedFailure) criterion(resp *http.Response) criterion {
+	cr := criterion{name: "expectFailure", passed: f.statuses[resp.StatusCode], detail: resp.Status}
+	if !cr.passed {
+		cr.detail = fmt.Sprintf("expected the request to fail, got %s", resp.Status)
+	}
+	return cr
+}
+
+// connectionFailed reports whether err means the endpoint could not be
+// reached at all: refused, not dialed or not resolved. A timeout or reset
+// after connecting is not what 'connectionError' expects.
+func (f *expectedFailure) connectionFailed(err error) bool {
+	if !f.connectionError {
+		return false
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.As(err, &opErr) && opErr.Op == "dial" ||
+		errors.As(err, &dnsErr)
+}
+
+// protocols lists the values accepted by 'requireProtocol'.
+var protocols = []string{"HTTP/1.0", "HTTP/1.1", "HTTP/2.0"}
+
+func parseRequireProtocol(config map[string]string) (string, error) {
+	want := config["requireProtocol"]
+	if want == "" || slices.Contains(protocols, want) {
+		return want, nil
+	}
+	return "", fmt.Errorf("invalid 'requireProtocol' %q: expected %s", want, strings.Join(protocols, ", "))
+}
+
+// protocolCriterion checks the protocol the server responded with.
+func protocolCriterion(want string, resp *http.Response) criterion {
+	cr := criterion{name: "protocol", passed: resp.Proto == want, detail: resp.Proto}
+	if !cr.passed {
+		cr.detail = fmt.Sprintf("got %s (expected %s)", resp.Proto, want)
+	}
+	return cr
+}
+
+// valueMatcher compares a value either literally or, when the expectation
+// is written as "regex:<pattern>", against a regular expression.
+type valueMatcher struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+func newValueMatcher(raw string) (valueMatcher, error) {
+	pattern, ok := strings.CutPrefix(raw, "regex:")
+	if !ok {
+		return valueMatcher{raw: raw}, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return valueMatcher{}, err
+	}
+	return valueMatcher{raw: raw, re: re}, nil
+}
+
+func (m valueMatcher) match(v string) bool {
+	if m.re != nil {
+		return m.re.MatchString(v)
+	}
+	return v == m.raw
+}
+
+// headerMatcher checks response headers against expected values.
+type headerMatcher map[string]valueMatcher
+
+// parseHeaderMatch reads the JSON object in key mapping header names to
+// expected values.
+func parseHeaderMatch(config map[string]string, key string) (headerMatcher, error) {
+	raw, ok := config[key]
+	if !ok {
+		return nil, nil
+	}
+	var expected map[string]string
+	if err := json.Unmarshal([]byte(raw), &expected); err != nil {
+		return nil, fmt.Errorf("invalid '%s' in config: %w", key, err)
+	}
+
+	matcher := make(headerMatcher, len(expected))
+	for name, value := range expected {
+		m, err := newValueMatcher(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' pattern for %q: %w", key, name, err)
+		}
+		matcher[http.CanonicalHeaderKey(name)] = m
+	}
+	return matcher, nil
+}
+
+// check returns a description of every header that is missing or does not
+// match, in a stable order. An empty result means all headers matched.
+// kind names the fields in the descriptions, e.g. "header" or "trailer".
+func (h headerMatcher) check(kind string, header http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var mismatches []string
+	for _, name := range names {
+		// Trailers announced but not sent are present without values
+		values := header[name]
+		if len(values) == 0 {
+			mismatches = append(mismatches, fmt.Sprintf("%s %s is missing (expected %q)", kind, name, h[name].raw))
+			continue
+		}
+		if !anyMatch(h[name], values) {
+			mismatches = append(mismatches, fmt.Sprintf("%s %s = %q (expected %q)", kind, name, strings.Join(values, ", "), h[name].raw))
+		}
+	}
+	return mismatches
+}
+
+func anyMatch(m valueMatcher, values []string) bool {
+	for _, v := range values {
+		if m.match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// criterion checks header against h and reports the mismatches as detail.
+func (h headerMatcher) criterion(name, kind string, header http.Header) criterion {
+	mismatches := h.check(kind, header)
+	if len(mismatches) == 0 {
+		return criterion{name: name, passed: true, detail: fmt.Sprintf("%d %s(s) matched", len(h), kind)}
+	}
+	return criterion{name: name, detail: strings.Join(mismatches, "; ")}
+}
+
+// headerAbsentCriterion checks that none of names is present in header,
+// listing any that are.
+func headerAbsentCriterion(names []string, header http.Header) criterion {
+	var present []string
+	for _, name := range names {
+		if values, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			present = append(present, fmt.Sprintf("header %s is present (%q)", http.CanonicalHeaderKey(name), strings.Join(values, ", ")))
+		}
+	}
+	if len(present) == 0 {
+		return criterion{name: "headerAbsent", passed: true, detail: fmt.Sprintf("%d header(s) absent", len(names))}
+	}
+	return criterion{name: "headerAbsent", detail: strings.Join(present, "; ")}
+}
+
+// bodySize is the allowed response body length from 'minBodyBytes' and
+// 'maxBodyBytes'; a negative bound is unset.
+type bodySize struct {
+	min, max int
+}
+
+func parseBodySize(config map[string]string) (*bodySize, error) {
+	if _, ok := config["minBodyBytes"]; !ok {
+		if _, ok := config["maxBodyBytes"]; !ok {
+			return nil, nil
+		}
+	}
+	size := &bodySize{min: -1, max: -1}
+	for key, bound := range map[string]*int{"minBodyBytes": &size.min, "maxBodyBytes": &size.max} {
+		v, err := configInt(config, key, -1)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := config[key]; ok && v < 0 {
+			return nil, fmt.Errorf("invalid '%s' in config: must not be negative", key)
+		}
+		*bound = v
+	}
+	if size.max >= 0 && size.min > size.max {
+		return nil, fmt.Errorf("'minBodyBytes' must not exceed 'maxBodyBytes'")
+	}
+	return size, nil
+}
+
+// criterion checks the body length against the allowed range.
+func (s bodySize) criterion(length int) criterion {
+	allowed := fmt.Sprintf("[%d, %d]", max(s.min, 0), s.max)
+	if s.max < 0 {
+		allowed = fmt.Sprintf("[%d, ∞)", s.min)
+	}
+	return criterion{
+		name:   "bodySize",
+		passed: length >= s.min && (s.max < 0 || length <= s.max),
+		detail: fmt.Sprintf("%d bytes (allowed %s)", length, allowed),
+	}
+}
+
+// bodyMatchCriterion checks the body against the 'bodyMatch' expression.
+func bodyMatchCriterion(re *regexp.Regexp, body []byte) criterion {
+	if match := re.Find(body); match != nil {
+		return criterion{name: "bodyMatch", passed: true, detail: fmt.Sprintf("matched %q", match)}
+	}
+	return criterion{name: "bodyMatch", detail: fmt.Sprintf("body does not match %q", re.String())}
+}
+
+// bodyAnyOf holds the 'expectedBodyAnyOf' entries. With 'anyOfMatchType'
+// regex each entry is a pattern searched for in the body, otherwise the
+// body must equal the entry exactly.
+type bodyAnyOf struct {
+	entries  []string
+	patterns []*regexp.Regexp
+}
+
+func parseBodyAnyOf(config map[string]string) (*bodyAnyOf, error) {
+	raw, ok := config["expectedBodyAnyOf"]
+	if !ok {
+		if _, ok := config["anyOfMatchType"]; ok {
+			return nil, fmt.Errorf("'anyOfMatchType' requires 'expectedBodyAnyOf'")
+		}
+		return nil, nil
+	}
+	a := &bodyAnyOf{}
+	if err := json.Unmarshal([]byte(raw), &a.entries); err != nil {
+		return nil, fmt.Errorf("invalid 'expectedBodyAnyOf' in config: %w", err)
+	}
+	if len(a.entries) == 0 {
+		return nil, fmt.Errorf("invalid 'expectedBodyAnyOf' in config: must contain at least one entry")
+	}
+	switch matchType := config["anyOfMatchType"]; matchType {
+	case "", "exact":
+	case "regex":
+		for i, entry := range a.entries {
+			re, err := regexp.Compile(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid 'expectedBodyAnyOf' entry %d: %w", i, err)
+			}
+			a.patterns = append(a.patterns, re)
+		}
+	default:
+		return nil, fmt.Errorf("invalid 'anyOfMatchType' %q: expected exact or regex", matchType)
+	}
+	return a, nil
+}
+
+// criterion passes when the body matches at least one entry, and reports
+// the first that does.
+func (a *bodyAnyOf) criterion(body []byte) criterion {
+	for i, entry := range a.entries {
+		matched := string(body) == entry
+		if a.patterns != nil {
+			matched = a.patterns[i].Match(body)
+		}
+		if matched {
+			return criterion{name: "expectedBodyAnyOf", passed: true, detail: fmt.Sprintf("matched entry %d %q", i, entry)}
+		}
+	}
+	return criterion{name: "expectedBodyAnyOf", detail: fmt.Sprintf("body matches none of the %d entries", len(a.entries))}
+}
+
+// readUntilMatch reads r until the body read so far matches re, or to EOF.
+func readUntilMatch(r io.Reader, re *regexp.Regexp) ([]byte, error) {
+	var body []byte
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		body = append(body, buf[:n]...)
+		if n > 0 && re.Match(body) {
+			return body, nil
+		}
+		if err == io.EOF {
+			return body, nil
+		}
+		if err != nil {
+			return body, err
+		}
+	}
+}
+
+// sha256Criterion compares the hex SHA-256 of the body with expected.
+func sha256Criterion(expected, actual string) criterion {
+	if strings.EqualFold(strings.TrimSpace(expected), actual) {
+		return criterion{name: "expectedSha256", passed: true, detail: actual}
+	}
+	return criterion{name: "expectedSha256", detail: fmt.Sprintf("expected %s, got %s", strings.ToLower(strings.TrimSpace(expected)), actual)}
+}
+
+// jsonPathCriterion evaluates path against the JSON body. Without an
+// expected value the path only has to resolve.
+func jsonPathCriterion(path string, expected *valueMatcher, body []byte) criterion {
+	cr := criterion{name: "jsonPath " + path}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		cr.detail = fmt.Sprintf("response is not valid JSON: %v", err)
+		return cr
+	}
+	value, err := evalJSONPath(doc, path)
+	if err != nil {
+		cr.detail = err.Error()
+		return cr
+	}
+
+	actual := jsonValueString(value)
+	if expected == nil {
+		cr.passed, cr.detail = true, actual
+		return cr
+	}
+	cr.passed = expected.match(actual)
+	if cr.passed {
+		cr.detail = actual
+	} else {
+		cr.detail = fmt.Sprintf("got %q (expected %q)", actual, expected.raw)
+	}
+	return cr
+}
+
+// correlationEchoCriterion checks that the response echoes the correlation
+// ID sent in header.
+func correlationEchoCriterion(header, id string, respHeader http.Header) criterion {
+	cr := criterion{name: "correlationEcho"}
+	switch echoed := respHeader.Get(header); echoed {
+	case id:
+		cr.passed, cr.detail = true, header+" echoed"
+	case "":
+		cr.detail = fmt.Sprintf("header %s is missing from the response", header)
+	default:
+		cr.detail = fmt.Sprintf("header %s = %q (expected %q)", header, echoed, id)
+	}
+	return cr
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeaderMatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Canary-Version", "v2.3.1")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		headerMatch string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{
+			name:        "exact match",
+			headerMatch: `{"x-canary-version": "v2.3.1"}`,
+			wantSuccess: true,
+		},
+		{
+			name:        "regex match",
+			headerMatch: `{"X-Canary-Version": "regex:^v2\\.", "Content-Type": "regex:json"}`,
+			wantSuccess: true,
+		},
+		{
+			name:        "value mismatch",
+			headerMatch: `{"X-Canary-Version": "v1.0.0"}`,
+			wantSuccess: false,
+			wantMessage: `header X-Canary-Version = "v2.3.1" (expected "v1.0.0")`,
+		},
+		{
+			name:        "missing header",
+			headerMatch: `{"X-Build": "123"}`,
+			wantSuccess: false,
+			wantMessage: `header X-Build is missing (expected "123")`,
+		},
+		{
+			name:        "status still checked",
+			path:        "/broken",
+			headerMatch: `{"X-Canary-Version": "v2.3.1"}`,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": server.URL + tt.path, "method": "GET", "headerMatch": tt.headerMatch})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	for _, raw := range []string{`["X-Build"]`, `{"X-Build": "regex:("}`} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "headerMatch": raw}); err == nil {
+			t.Errorf("Expected error for headerMatch %s", raw)
+		}
+	}
+}
+
+func TestHeaderAbsent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Canary-Version", "v2.3.1")
+		if r.URL.Path == "/debug" {
+			w.Header().Set("X-Debug-Token", "abc")
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		config      map[string]string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{name: "absent", config: map[string]string{"headerAbsent": "X-Debug-Token, Server-Timing"}, wantSuccess: true, wantMessage: "[PASS] headerAbsent: 2 header(s) absent"},
+		{name: "present", path: "/debug", config: map[string]string{"headerAbsent": "x-debug-token"}, wantMessage: `[FAIL] headerAbsent: header X-Debug-Token is present ("abc")`},
+		{name: "with headerMatch", path: "/debug", config: map[string]string{"headerAbsent": "X-Debug-Token", "headerMatch": `{"X-Canary-Version": "v2.3.1"}`}, wantMessage: "[PASS] headerMatch"},
+		{name: "any logic", path: "/debug", config: map[string]string{"headerAbsent": "X-Debug-Token", "headerMatch": `{"X-Canary-Version": "v2.3.1"}`, "matchLogic": "any"}, wantSuccess: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMatchLogic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Canary-Version", "v2")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		logic       string
+		headerMatch string
+		wantSuccess bool
+		wantMessage []string
+	}{
+		{
+			logic:       "",
+			headerMatch: `{"X-Canary-Version": "v2"}`,
+			wantSuccess: false,
+			wantMessage: []string{"Criteria (all):", "[FAIL] status: 503 Service Unavailable", "[PASS] headerMatch"},
+		},
+		{
+			logic:       "any",
+			headerMatch: `{"X-Canary-Version": "v2"}`,
+			wantSuccess: true,
+			wantMessage: []string{"Criteria (any):", "[FAIL] status", "[PASS] headerMatch: 1 header(s) matched"},
+		},
+		{
+			logic:       "any",
+			headerMatch: `{"X-Canary-Version": "v1"}`,
+			wantSuccess: false,
+			wantMessage: []string{"[FAIL] status", `[FAIL] headerMatch: header X-Canary-Version = "v2" (expected "v1")`},
+		},
+	}
+
+	for _, tt := range tests {
+		output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "matchLogic": tt.logic, "headerMatch": tt.headerMatch})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if output.Success != tt.wantSuccess {
+			t.Errorf("matchLogic %q: Success = %v, want %v", tt.logic, output.Success, tt.wantSuccess)
+		}
+		for _, want := range tt.wantMessage {
+			if !strings.Contains(output.Message, want) {
+				t.Errorf("matchLogic %q: Message = %q, want it to contain %q", tt.logic, output.Message, want)
+			}
+		}
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "matchLogic": "most"}); err == nil {
+		t.Error("Expected error for unknown matchLogic")
+	}
+}
+
+func TestExpectedSha256(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("static asset v1"))
+	}))
+	defer server.Close()
+
+	const want = "8b4b5dd3d6e1aa34cdc2d9b1c3ab0d5479c0ec63b9fd4a2ad9e5a356b5dbd2a0"
+	sum := sha256.Sum256([]byte("static asset v1"))
+	actual := hex.EncodeToString(sum[:])
+
+	// The hash is reported even without an expectation
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.BodySha256 != actual {
+		t.Errorf("BodySha256 = %q, want %q", output.BodySha256, actual)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "expectedSha256": strings.ToUpper(actual)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected matching hash to pass, got: %v", output.Message)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "expectedSha256": want})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "expected "+want+", got "+actual) {
+		t.Errorf("Expected mismatch with both hashes, got: %v", output.Message)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "HEAD", "expectedSha256": want}); err == nil {
+		t.Error("Expected error for expectedSha256 on a HEAD request")
+	}
+}
+
+func TestBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			return
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		config      map[string]string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{name: "within range", config: map[string]string{"minBodyBytes": "1", "maxBodyBytes": "10"}, wantSuccess: true, wantMessage: "[PASS] bodySize: 10 bytes (allowed [1, 10])"},
+		{name: "too large", config: map[string]string{"maxBodyBytes": "9"}, wantMessage: "[FAIL] bodySize: 10 bytes (allowed [0, 9])"},
+		{name: "empty body", path: "/empty", config: map[string]string{"minBodyBytes": "1"}, wantMessage: "[FAIL] bodySize: 0 bytes (allowed [1, ∞))"},
+		{name: "any logic", path: "/empty", config: map[string]string{"minBodyBytes": "1", "matchLogic": "any"}, wantSuccess: true, wantMessage: "[PASS] status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	for _, config := range []map[string]string{
+		{"minBodyBytes": "-1"},
+		{"maxBodyBytes": "ten"},
+		{"minBodyBytes": "5", "maxBodyBytes": "4"},
+		{"minBodyBytes": "1", "method": "HEAD"},
+	} {
+		config["uri"] = server.URL
+		if config["method"] == "" {
+			config["method"] = "GET"
+		}
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestRequireProtocol(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		require     string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{require: "", wantSuccess: true},
+		{require: "HTTP/1.1", wantSuccess: true, wantMessage: "[PASS] protocol: HTTP/1.1"},
+		{require: "HTTP/2.0", wantMessage: "[FAIL] protocol: got HTTP/1.1 (expected HTTP/2.0)"},
+	}
+	for _, tt := range tests {
+		output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "requireProtocol": tt.require})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if output.Protocol != "HTTP/1.1" {
+			t.Errorf("Protocol = %q, want HTTP/1.1", output.Protocol)
+		}
+		if output.Success != tt.wantSuccess || !strings.Contains(output.Message, tt.wantMessage) {
+			t.Errorf("requireProtocol %q: success=%v message=%q", tt.require, output.Success, output.Message)
+		}
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "requireProtocol": "h2"}); err == nil {
+		t.Error("Expected error for invalid requireProtocol")
+	}
+}
+
+func TestCloseOnMatch(t *testing.T) {
+	disconnected := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { disconnected <- struct{}{} }()
+		w.Write([]byte("starting\nstate=READY\n"))
+		// Keep streaming until the client goes away
+		for {
+			w.Write([]byte("tick\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "bodyMatch": "state=READY", "closeOnMatch": "true", "timeout": "5s"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !strings.Contains(output.Message, `[PASS] bodyMatch: matched "state=READY"`) {
+		t.Errorf("Expected the match to pass, got: %v", output.Message)
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Error("Expected the connection to be closed after the match")
+	}
+
+	// Without closeOnMatch the stream is read until the timeout
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "bodyMatch": "state=READY", "timeout": "100ms"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "Body read error") {
+		t.Errorf("Expected the endless stream to time out, got: %v", output.Message)
+	}
+	<-disconnected
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "closeOnMatch": "true"}); err == nil {
+		t.Error("Expected error for closeOnMatch without bodyMatch")
+	}
+}
+
+func TestFailOnEmptyBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		failOnEmpty string
+		wantSuccess bool
+	}{
+		{name: "empty body", path: "/empty", failOnEmpty: "true"},
+		{name: "non-empty body", failOnEmpty: "true", wantSuccess: true},
+		{name: "off by default", path: "/empty", wantSuccess: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET"}
+			if tt.failOnEmpty != "" {
+				config["failOnEmptyBody"] = tt.failOnEmpty
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !tt.wantSuccess && output.FailureReason != "empty response body (status 200)" {
+				t.Errorf("FailureReason = %q", output.FailureReason)
+			}
+		})
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "HEAD", "failOnEmptyBody": "true"}); err == nil {
+		t.Error("Expected error for failOnEmptyBody with HEAD")
+	}
+}
+
+func TestExpectedBodyAnyOf(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/")))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		config      map[string]string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{name: "exact match", path: "/degraded", config: map[string]string{"expectedBodyAnyOf": `["ok", "degraded"]`}, wantSuccess: true, wantMessage: `[PASS] expectedBodyAnyOf: matched entry 1 "degraded"`},
+		{name: "exact is not a substring", path: "/okay", config: map[string]string{"expectedBodyAnyOf": `["ok", "degraded"]`}, wantMessage: "[FAIL] expectedBodyAnyOf: body matches none of the 2 entries"},
+		{name: "regex match", path: "/node-7-ready", config: map[string]string{"expectedBodyAnyOf": `["^ok$", "node-[0-9]+-ready"]`, "anyOfMatchType": "regex"}, wantSuccess: true, wantMessage: "matched entry 1"},
+		{name: "regex no match", path: "/down", config: map[string]string{"expectedBodyAnyOf": `["^ok$"]`, "anyOfMatchType": "regex"}, wantMessage: "body matches none of the 1 entries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	for _, config := range []map[string]string{
+		{"expectedBodyAnyOf": `"ok"`},
+		{"expectedBodyAnyOf": `[]`},
+		{"expectedBodyAnyOf": `["("]`, "anyOfMatchType": "regex"},
+		{"expectedBodyAnyOf": `["ok"]`, "anyOfMatchType": "glob"},
+		{"anyOfMatchType": "exact"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestExpectFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/removed":
+			w.WriteHeader(http.StatusGone)
+		case "/hanging":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		case "/still-here":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		uri         string
+		expect      string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{name: "connection refused", uri: "http://127.0.0.1:1", expect: "connectionError", wantSuccess: true, wantMessage: "Request failed as expected:"},
+		{name: "timeout is not a connection error", uri: server.URL + "/hanging", expect: "connectionError", wantMessage: "Request error:"},
+		{name: "connection error not expected", uri: "http://127.0.0.1:1", expect: "404, 410", wantMessage: "Request error:"},
+		{name: "expected status", uri: server.URL + "/removed", expect: "connectionError, 404, 410", wantSuccess: true, wantMessage: "[PASS] expectFailure: 410 Gone"},
+		{name: "endpoint still serves", uri: server.URL + "/still-here", expect: "connectionError, 404", wantMessage: "[FAIL] expectFailure: expected the request to fail, got 200 OK"},
+		{name: "other failure status", uri: server.URL + "/removed", expect: "404", wantMessage: "got 410 Gone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": tt.uri, "method": "GET", "expectFailure": tt.expect, "timeout": "100ms"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	for _, config := range []map[string]string{
+		{"expectFailure": "timeout"},
+		{"expectFailure": "700"},
+		{"expectFailure": "404", "celExpression": "status == 404"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestTrailerMatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message, X-Checksum")
+		w.Write([]byte("streamed body"))
+		w.(http.Flusher).Flush()
+		status := "0"
+		if r.URL.Path == "/failed" {
+			status = "14"
+			w.Header().Set("Grpc-Message", "unavailable")
+		}
+		w.Header().Set("Grpc-Status", status)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		match       string
+		wantSuccess bool
+		wantDetail  string
+	}{
+		{name: "matched", match: `{"grpc-status": "0"}`, wantSuccess: true, wantDetail: "[PASS] trailerMatch: 1 trailer(s) matched"},
+		{name: "mismatch", path: "/failed", match: `{"Grpc-Status": "0"}`, wantDetail: `trailer Grpc-Status = "14" (expected "0")`},
+		{name: "announced but not sent", match: `{"X-Checksum": "regex:.+"}`, wantDetail: "trailer X-Checksum is missing"},
+		{name: "never announced", match: `{"X-Other": "1"}`, wantDetail: "trailer X-Other is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": server.URL + tt.path, "method": "GET", "trailerMatch": tt.match})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantDetail) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantDetail)
+			}
+		})
+	}
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL + "/failed", "method": "GET", "includeTrailers": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]string{"Grpc-Status": "14", "Grpc-Message": "unavailable"}
+	if !reflect.DeepEqual(output.Trailers, want) {
+		t.Errorf("Trailers = %v, want %v", output.Trailers, want)
+	}
+	if !strings.Contains(output.Message, "Trailers: Grpc-Message: unavailable, Grpc-Status: 14") {
+		t.Errorf("Message = %q, want the trailers", output.Message)
+	}
+
+	for _, config := range []map[string]string{
+		{"trailerMatch": `{"Grpc-Status": "0"}`, "method": "HEAD"},
+		{"trailerMatch": `{"Grpc-Status": "0"}`, "method": "GET", "sse": "true"},
+		{"includeTrailers": "true", "method": "GET", "bodyMatch": "x", "closeOnMatch": "true"},
+		{"trailerMatch": `["Grpc-Status"]`, "method": "GET"},
+	} {
+		config["uri"] = server.URL
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
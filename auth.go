@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-ntlmssp"
+)
+
+// ---- Authentication ----
+
+// serviceAccountTokenFile is where Kubernetes mounts the pod's service
+// account token; 'tokenFile: auto' reads it.
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// applyAuth sets the credentials for the configured 'authScheme', or the
+// bearer token from 'tokenFile'.
+func applyAuth(req *http.Request, config map[string]string) error {
+	if path := config["tokenFile"]; path != "" {
+		if config["authScheme"] != "" {
+			return fmt.Errorf("'tokenFile' cannot be combined with 'authScheme'")
+		}
+		token, err := readTokenFile(path)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+
+	switch scheme := config["authScheme"]; scheme {
+	case "":
+		return nil
+	case "ntlm":
+		user, password := config["ntlmUser"], config["ntlmPassword"]
+		if user == "" || password == "" {
+			return fmt.Errorf("'authScheme' ntlm requires 'ntlmUser' and 'ntlmPassword'")
+		}
+		if domain := config["ntlmDomain"]; domain != "" {
+			user = domain + `\` + user
+		}
+		// The NTLM transport picks the credentials up from basic auth and
+		// replaces them with the NTLM handshake.
+		req.SetBasicAuth(user, password)
+		return nil
+	case "oauth2":
+		// The token is set by oauth2Transport, once it has been fetched
+		_, err := newOAuth2Config(config)
+		return err
+	default:
+		return fmt.Errorf("invalid 'authScheme' %q: expected ntlm or oauth2", scheme)
+	}
+}
+
+// readTokenFile reads a bearer token, resolving "auto" to the service
+// account token. The file is read on every Run so rotated tokens are used.
+func readTokenFile(path string) (string, error) {
+	if path == "auto" {
+		path = serviceAccountTokenFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read 'tokenFile': %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("'tokenFile' %s is empty", path)
+	}
+	return token, nil
+}
+
+// ntlmTransport performs the NTLM/Negotiate handshake on top of next.
+// Requests are cloned first because the negotiator rewrites their
+// Authorization header, which retries still need.
+type ntlmTransport struct {
+	next http.RoundTripper
+}
+
+func (t *ntlmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return ntlmssp.Negotiator{RoundTripper: t.next}.RoundTrip(req.Clone(req.Context()))
+}
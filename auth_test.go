@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNTLMHandshake(t *testing.T) {
+	var negotiated bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		switch {
+		case auth == "":
+			w.Header().Set("WWW-Authenticate", "NTLM")
+			w.WriteHeader(http.StatusUnauthorized)
+		case strings.HasPrefix(auth, "NTLM TlRMTVNTUAAB"): // NTLMSSP type 1 (negotiate) message
+			negotiated = true
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":          server.URL,
+		"method":       "GET",
+		"authScheme":   "ntlm",
+		"ntlmUser":     "probe",
+		"ntlmPassword": "secret",
+		"ntlmDomain":   "CORP",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !negotiated {
+		t.Errorf("Expected NTLM negotiation, got: %v", output.Message)
+	}
+}
+
+func TestAuthSchemeValidation(t *testing.T) {
+	for _, config := range []map[string]string{
+		{"authScheme": "kerberos"},
+		{"authScheme": "ntlm", "ntlmUser": "probe"},
+		{"authScheme": "ntlm", "ntlmPassword": "secret"},
+	} {
+		config["uri"] = "http://127.0.0.1"
+		config["method"] = "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestTokenFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer rotated-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	config := map[string]string{"uri": server.URL, "method": "GET", "tokenFile": path}
+
+	// Each Run re-reads the file, so a rotated token is picked up
+	for _, tt := range []struct {
+		token       string
+		wantSuccess bool
+	}{
+		{token: "rotated-1\n", wantSuccess: false},
+		{token: "rotated-2\n", wantSuccess: true},
+	} {
+		if err := os.WriteFile(path, []byte(tt.token), 0o600); err != nil {
+			t.Fatalf("Failed to write token: %v", err)
+		}
+		output, err := runPlugin(t, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if output.Success != tt.wantSuccess {
+			t.Errorf("token %q: Success = %v, want %v", tt.token, output.Success, tt.wantSuccess)
+		}
+	}
+}
+
+func TestTokenFileErrors(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+
+	tests := []struct {
+		config  map[string]string
+		wantErr string
+	}{
+		{config: map[string]string{"tokenFile": filepath.Join(t.TempDir(), "missing")}, wantErr: "failed to read 'tokenFile'"},
+		{config: map[string]string{"tokenFile": empty}, wantErr: "is empty"},
+		{config: map[string]string{"tokenFile": empty, "authScheme": "ntlm"}, wantErr: "cannot be combined"},
+	}
+	for _, tt := range tests {
+		tt.config["uri"] = "http://127.0.0.1"
+		tt.config["method"] = "GET"
+		_, err := runPlugin(t, tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("config %v: expected error containing %q, got %v", tt.config, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ---- Batch Mode ----
+
+// batchKeys configure the batch as a whole, so entries do not inherit them.
+var batchKeys = append([]string{"requireConnectionReuse"}, sequenceKeys...)
+
+// parseBatch decodes the 'requests' array and merges each entry over the
+// top-level config: top-level keys are defaults and entry keys override
+// them one by one. Entry values may be strings or any JSON value, which is
+// passed on as its JSON text (so "headers" can be written as an object).
+func parseBatch(config map[string]string) ([]map[string]string, error) {
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(config["requests"]), &entries); err != nil {
+		return nil, fmt.Errorf("invalid 'requests' in config: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("invalid 'requests' in config: must contain at least one request")
+	}
+
+	merged := make([]map[string]string, len(entries))
+	for i, entry := range entries {
+		if _, ok := entry["requests"]; ok {
+			return nil, fmt.Errorf("requests[%d]: 'requests' cannot be nested", i)
+		}
+		m := make(map[string]string, len(config)+len(entry))
+		for key, value := range config {
+			if key != "requests" && !slices.Contains(batchKeys, key) {
+				m[key] = value
+			}
+		}
+		for key, raw := range entry {
+			value, err := batchValue(raw)
+			if err != nil {
+				return nil, fmt.Errorf("requests[%d]: invalid '%s': %w", i, key, err)
+			}
+			m[key] = value
+		}
+		// Templates are rendered per request, but checked up front
+		if err := renderBatchTemplates(m, nil, true); err != nil {
+			return nil, fmt.Errorf("requests[%d]: %w", i, err)
+		}
+		merged[i] = m
+	}
+	return merged, nil
+}
+
+func batchValue(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return "", err
+	}
+	return compact.String(), nil
+}
+
+// runBatch runs every request of a batch in order. The step succeeds only
+// when all of them do; the message reports each request in turn, with its
+// whole message in full output and only its status in summary output.
+func (p *HTTPPlugin) runBatch(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	configs, err := parseBatch(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	mode, err := parseOutputMode(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	sequence, err := parseSequenceAssertion(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	requireReuse, err := configBool(config, "requireConnectionReuse", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	result := PluginOutput{Success: true}
+	var sections []string
+	var sequenceErr error
+	var responses []map[string]interface{}
+	for i, entry := range configs {
+		var output PluginOutput
+		if err := renderBatchTemplates(entry, responses, false); err != nil {
+			// Usually an earlier request did not return what this one needs
+			output = PluginOutput{FailureReason: err.Error(), Message: fmt.Sprintf("Template error: %v", err)}
+		} else if output, err = p.runEntry(ctx, entry); err != nil {
+			return PluginOutput{}, fmt.Errorf("requests[%d]: %w", i, err)
+		}
+		responses = append(responses, newBatchResponse(output))
+		if requireReuse {
+			result.ConnectionReuse = append(result.ConnectionReuse, output.reused)
+		}
+		if sequence != nil && sequenceErr == nil {
+			v, err := sequence.value(output.body)
+			if err != nil {
+				sequenceErr = fmt.Errorf("requests[%d]: %w", i, err)
+			} else {
+				result.Sequence = append(result.Sequence, v)
+			}
+		}
+		if !output.Success && result.Success {
+			result.Success = false
+			result.FailureReason = fmt.Sprintf("requests[%d] failed", i)
+			if output.FailureReason != "" {
+				result.FailureReason += ": " + output.FailureReason
+			}
+		}
+
+		outcome := "PASS"
+		if !output.Success {
+			outcome = "FAIL"
+		}
+		section := fmt.Sprintf("[%s] requests[%d] %s %s", outcome, i, entry["method"], entry["uri"])
+		switch {
+		case mode == outputModeSummary:
+			if status := messageStatus(output.Message); status != "" {
+				section += " (" + status + ")"
+			}
+		case output.Message != "":
+			section += "\n" + output.Message
+		}
+		sections = append(sections, section)
+	}
+	if sequence != nil {
+		sections = append(sections, checkSequence(&result, sequence, sequenceErr))
+	}
+	if requireReuse {
+		sections = append(sections, checkConnectionReuse(&result))
+	}
+	switch mode {
+	case outputModeMinimal:
+	case outputModeSummary:
+		result.Message = strings.Join(sections, "\n")
+	default:
+		result.Message = strings.Join(sections, "\n\n")
+	}
+	return result, nil
+}
+
+// messageStatus returns the status reported on the first line of an
+// entry's message, e.g. "200 OK", or "" when there is none.
+func messageStatus(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	status, ok := strings.CutPrefix(line, "Status: ")
+	if !ok {
+		return ""
+	}
+	return status
+}
+
+// checkSequence evaluates the 'sequenceAssertion' over the values
+// collected in result, failing it on the first violation, and returns the
+// report for the message. err is why a value could not be extracted.
+func checkSequence(result *PluginOutput, sequence *sequenceAssertion, err error) string {
+	values := make([]string, len(result.Sequence))
+	for i, v := range result.Sequence {
+		values[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	report := fmt.Sprintf("Sequence %s: [%s]", sequence.path, strings.Join(values, ", "))
+
+	var reason string
+	if err != nil {
+		reason = fmt.Sprintf("sequenceAssertion: %v", err)
+	} else if i := sequence.violation(result.Sequence); i >= 0 {
+		reason = fmt.Sprintf("sequenceAssertion: requests[%d] %s = %s after %s, not %s", i, sequence.path, values[i], values[i-1], sequence.order())
+	}
+	if reason == "" {
+		return fmt.Sprintf("[PASS] %s (%s)", report, sequence.order())
+	}
+	if result.Success {
+		result.Success = false
+		result.FailureReason = reason
+	}
+	return fmt.Sprintf("[FAIL] %s\n%s", report, reason)
+}
+
+// checkConnectionReuse requires every request after the first, which
+// opens the connection, to have reused one, failing result otherwise. It
+// returns the report for the message.
+func checkConnectionReuse(result *PluginOutput) string {
+	var fresh []string
+	for i, reused := range result.ConnectionReuse[1:] {
+		if !reused {
+			fresh = append(fresh, fmt.Sprintf("requests[%d]", i+1))
+		}
+	}
+	report := fmt.Sprintf("Connection reuse: %v", result.ConnectionReuse)
+	if len(fresh) == 0 {
+		return "[PASS] " + report
+	}
+	reason := fmt.Sprintf("requireConnectionReuse: %s opened a new connection", strings.Join(fresh, ", "))
+	if result.Success {
+		result.Success = false
+		result.FailureReason = reason
+	}
+	return fmt.Sprintf("[FAIL] %s\n%s", report, reason)
+}
+
+// runKeys report on the Run as a whole, so the entries of a wrapper such
+// as 'requests' do not inherit them.
+var runKeys = []string{"pushgatewayUrl", "pushgatewayJob", "outputFormat", "metricValue"}
+
+// runEntry runs a single, already merged, entry of a wrapper.
+func (p *HTTPPlugin) runEntry(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	entry := make(map[string]string, len(config))
+	for key, value := range config {
+		if !slices.Contains(runKeys, key) {
+			entry[key] = value
+		}
+	}
+	return p.doRequest(ctx, PluginInput{Config: entry})
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchOverrides(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+		case "/team":
+			w.Write([]byte(r.Header.Get("X-Team")))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		requests    string
+		wantSuccess bool
+		wantMessage []string
+	}{
+		{
+			name:        "inherits top-level keys",
+			requests:    `[{"uri": "` + server.URL + `/team"}, {"uri": "` + server.URL + `/slow"}]`,
+			wantSuccess: true,
+			wantMessage: []string{"[PASS] requests[0] GET " + server.URL + "/team\nStatus: 200 OK\nBody: canary", "[PASS] requests[1]"},
+		},
+		{
+			name:        "entry overrides a string key",
+			requests:    `[{"uri": "` + server.URL + `/team", "headers": "{\"X-Team\": \"stable\"}"}]`,
+			wantSuccess: true,
+			wantMessage: []string{"Body: stable"},
+		},
+		{
+			name:        "entry overrides with a JSON value",
+			requests:    `[{"uri": "` + server.URL + `/team", "headers": {"X-Team": "blue"}}]`,
+			wantSuccess: true,
+			wantMessage: []string{"Body: blue"},
+		},
+		{
+			name:        "per-request timeout takes effect",
+			requests:    `[{"uri": "` + server.URL + `/team"}, {"uri": "` + server.URL + `/slow", "timeout": "50ms"}]`,
+			wantSuccess: false,
+			wantMessage: []string{"[PASS] requests[0]", "[FAIL] requests[1]", "Client.Timeout exceeded"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{
+				"method":   "GET",
+				"headers":  `{"X-Team": "canary"}`,
+				"timeout":  "5s",
+				"requests": tt.requests,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			for _, want := range tt.wantMessage {
+				if !strings.Contains(output.Message, want) {
+					t.Errorf("Message = %q, want it to contain %q", output.Message, want)
+				}
+			}
+			if !tt.wantSuccess && !strings.HasPrefix(output.FailureReason, "requests[1] failed") {
+				t.Errorf("FailureReason = %q, want it to name requests[1]", output.FailureReason)
+			}
+		})
+	}
+}
+
+func TestBatchOutputMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	requests := fmt.Sprintf(`[{"uri": %q}, {"uri": %q}]`, server.URL, server.URL+"/missing")
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: "summary", want: fmt.Sprintf("[PASS] requests[0] GET %[1]s (200 OK)\n[FAIL] requests[1] GET %[1]s/missing (404 Not Found)", server.URL)},
+		{mode: "minimal", want: ""},
+	}
+	for _, tt := range tests {
+		output, err := runPlugin(t, map[string]string{"method": "GET", "requests": requests, "outputMode": tt.mode})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if output.Success || output.FailureReason == "" {
+			t.Errorf("outputMode %s: expected the batch to fail with a reason, got %+v", tt.mode, output)
+		}
+		if output.Message != tt.want {
+			t.Errorf("outputMode %s: Message = %q, want %q", tt.mode, output.Message, tt.want)
+		}
+	}
+}
+
+func TestBatchInvalid(t *testing.T) {
+	tests := []struct {
+		requests string
+		wantErr  string
+	}{
+		{requests: `{}`, wantErr: "invalid 'requests'"},
+		{requests: `[]`, wantErr: "at least one request"},
+		{requests: `[{"requests": []}]`, wantErr: "cannot be nested"},
+		{requests: `[{"uri": "http://localhost"}]`, wantErr: "requests[0]: missing 'uri' or 'method'"},
+		{requests: `[{"uri": "http://localhost", "method": "GET", "timeout": "0s"}]`, wantErr: "invalid 'timeout'"},
+	}
+
+	for _, tt := range tests {
+		_, err := runPlugin(t, map[string]string{"requests": tt.requests})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("requests %s: expected error containing %q, got %v", tt.requests, tt.wantErr, err)
+		}
+	}
+}
+
+func TestSequenceAssertion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			w.Write([]byte("no counter"))
+			return
+		}
+		fmt.Fprintf(w, `{"rollout": {"revision": %s}}`, r.URL.Query().Get("rev"))
+	}))
+	defer server.Close()
+
+	requests := func(revs ...string) string {
+		var entries []string
+		for _, rev := range revs {
+			entries = append(entries, `{"uri": "`+server.URL+`/?rev=`+rev+`"}`)
+		}
+		return "[" + strings.Join(entries, ", ") + "]"
+	}
+
+	tests := []struct {
+		name        string
+		requests    string
+		order       string
+		wantSuccess bool
+		wantMessage string
+		wantReason  string
+	}{
+		{name: "non-decreasing", requests: requests("1", "2", "2", "5"), wantSuccess: true, wantMessage: "[PASS] Sequence rollout.revision: [1, 2, 2, 5] (non-decreasing)"},
+		{name: "strictly increasing", requests: requests("1", "2", "3"), order: "increasing", wantSuccess: true, wantMessage: "[1, 2, 3] (increasing)"},
+		{name: "repeat when increasing", requests: requests("1", "2", "2"), order: "increasing", wantReason: "sequenceAssertion: requests[2] rollout.revision = 2 after 2, not increasing"},
+		{name: "decrease", requests: requests("3", "7", "5", "9"), wantMessage: "[FAIL] Sequence rollout.revision: [3, 7, 5, 9]", wantReason: "sequenceAssertion: requests[2] rollout.revision = 5 after 7, not non-decreasing"},
+		{name: "numeric string", requests: requests("%2210%22", "%2211%22"), wantSuccess: true},
+		{name: "not a number", requests: requests("1", "true"), wantReason: "sequenceAssertion: requests[1]: rollout.revision is a boolean, not a number"},
+		{name: "not json", requests: `[{"uri": "` + server.URL + `/text"}]`, wantReason: "sequenceAssertion: requests[0]: response is not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"method": "GET", "requests": tt.requests, "sequenceAssertion": "rollout.revision"}
+			if tt.order != "" {
+				config["sequenceOrder"] = tt.order
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+			if !strings.HasPrefix(output.FailureReason, tt.wantReason) {
+				t.Errorf("FailureReason = %q, want prefix %q", output.FailureReason, tt.wantReason)
+			}
+		})
+	}
+
+	for _, config := range []map[string]string{
+		{"uri": server.URL, "method": "GET", "sequenceAssertion": "rollout.revision"},
+		{"method": "GET", "requests": requests("1"), "sequenceOrder": "increasing"},
+		{"method": "GET", "requests": requests("1"), "sequenceAssertion": "rollout.revision", "sequenceOrder": "up"},
+	} {
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestBatchTemplates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("X-Session", "sess-9")
+			w.Write([]byte(`{"token": "abc123", "orders": [{"id": 7}, {"id": 8}]}`))
+		case "/orders":
+			if r.Header.Get("Authorization") != "Bearer abc123" || r.Header.Get("X-Session") != "sess-9" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"order": 8, "after": 200}` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	requests := fmt.Sprintf(`[
+		{"uri": "%[1]s/login", "method": "POST"},
+		{"uri": "%[1]s/orders", "method": "POST",
+		 "headers": {"Authorization": "Bearer {{.responses.0.json.token}}", "X-Session": "{{index .responses.0.headers \"X-Session\"}}"},
+		 "body": "{\"order\": {{.responses.0.json.orders.1.id}}, \"after\": {{.responses.0.status}}}"}
+	]`, server.URL)
+	output, err := runPlugin(t, map[string]string{"requests": requests})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected the chained request to succeed, got %s", output.Message)
+	}
+
+	unresolved := fmt.Sprintf(`[
+		{"uri": "%[1]s/login", "method": "POST"},
+		{"uri": "%[1]s/orders", "method": "POST", "body": "{{.responses.0.json.missing}}"},
+		{"uri": "%[1]s/orders", "method": "POST", "body": "{{.responses.5.json.token}}"}
+	]`, server.URL)
+	output, err = runPlugin(t, map[string]string{"requests": unresolved})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.FailureReason, `requests[1] failed: failed to render 'body'`) || !strings.Contains(output.FailureReason, `"missing"`) {
+		t.Errorf("FailureReason = %q, want the unresolved reference", output.FailureReason)
+	}
+	if !strings.Contains(output.Message, "[FAIL] requests[2]") || !strings.Contains(output.Message, "index out of range: 5") {
+		t.Errorf("Message = %q, want the out of range reference reported", output.Message)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"requests": fmt.Sprintf(`[{"uri": "%s", "method": "GET", "body": "{{.responses.0"}]`, server.URL)}); err == nil {
+		t.Error("Expected error for a malformed template")
+	}
+}
+
+func TestRequireConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/close" {
+			w.Header().Set("Connection", "close")
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	requests := fmt.Sprintf(`[{"uri": "%[1]s/a"}, {"uri": "%[1]s/b"}, {"uri": "%[1]s/c"}]`, server.URL)
+	output, err := runPlugin(t, map[string]string{"method": "GET", "requests": requests, "requireConnectionReuse": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || fmt.Sprint(output.ConnectionReuse) != "[false true true]" || !strings.Contains(output.Message, "[PASS] Connection reuse: [false true true]") {
+		t.Errorf("Expected the batch to reuse its connection, got %+v", output)
+	}
+
+	// The server closes the connection after /close, so /c needs a new one
+	requests = fmt.Sprintf(`[{"uri": "%[1]s/a"}, {"uri": "%[1]s/close"}, {"uri": "%[1]s/c"}]`, server.URL)
+	output, err = runPlugin(t, map[string]string{"method": "GET", "requests": requests, "requireConnectionReuse": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || output.FailureReason != "requireConnectionReuse: requests[2] opened a new connection" {
+		t.Errorf("Expected the batch to fail on the new connection, got %+v", output)
+	}
+
+	for _, config := range []map[string]string{
+		{"uri": server.URL, "requireConnectionReuse": "true"},
+		{"requests": requests, "requireConnectionReuse": "sometimes"},
+	} {
+		config["method"] = "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
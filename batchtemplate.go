@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// ---- Batch Templates ----
+
+var (
+	templateAction = regexp.MustCompile(`{{.*?}}`)
+	// A field chain such as .responses.0.json.token; the numeric segments
+	// are rewritten into index calls, which text/template requires.
+	templateChain = regexp.MustCompile(`(\.[A-Za-z_]\w*|\.\d+)+`)
+)
+
+// newBatchResponse is what a template sees of an earlier response, as
+// .responses.N: its status, headers, body and the body parsed as JSON
+// (nil when it is not JSON).
+func newBatchResponse(output PluginOutput) map[string]interface{} {
+	headers := make(map[string]string, len(output.header))
+	for name, values := range output.header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	var doc interface{}
+	if err := json.Unmarshal(output.body, &doc); err != nil {
+		doc = nil
+	}
+	return map[string]interface{}{
+		"status":  output.status,
+		"headers": headers,
+		"body":    string(output.body),
+		"json":    doc,
+	}
+}
+
+// parseBatchTemplate parses a templated entry value. It returns nil when
+// the value has no template actions.
+func parseBatchTemplate(key, value string) (*template.Template, error) {
+	if !strings.Contains(value, "{{") {
+		return nil, nil
+	}
+	rewritten := templateAction.ReplaceAllStringFunc(value, func(action string) string {
+		return templateChain.ReplaceAllStringFunc(action, indexChain)
+	})
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(rewritten)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template in '%s': %w", key, err)
+	}
+	return tmpl, nil
+}
+
+// indexChain rewrites numeric segments of a field chain into index calls,
+// e.g. .responses.0.json.items.1 into (index (index .responses 0).json.items 1).
+func indexChain(chain string) string {
+	segments := strings.Split(chain, ".")[1:]
+	out := ""
+	for _, segment := range segments {
+		if segment[0] >= '0' && segment[0] <= '9' {
+			if out == "" {
+				// A plain number such as 1.5, not a field chain
+				return chain
+			}
+			out = fmt.Sprintf("(index %s %s)", out, segment)
+			continue
+		}
+		out += "." + segment
+	}
+	return out
+}
+
+// renderBatchTemplates renders the body and header values of entry
+// against the earlier responses, so a request can use e.g. a token
+// returned by a login before it. References that do not resolve are
+// errors. With check, templates are only parsed.
+func renderBatchTemplates(entry map[string]string, responses []map[string]interface{}, check bool) error {
+	data := map[string]interface{}{"responses": responses}
+	render := func(key, value string) (string, error) {
+		tmpl, err := parseBatchTemplate(key, value)
+		if err != nil || tmpl == nil || check {
+			return value, err
+		}
+		var rendered strings.Builder
+		if err := tmpl.Execute(&rendered, data); err != nil {
+			return "", fmt.Errorf("failed to render '%s': %w", key, err)
+		}
+		return rendered.String(), nil
+	}
+
+	body, err := render("body", entry["body"])
+	if err != nil {
+		return err
+	}
+	if _, ok := entry["body"]; ok {
+		entry["body"] = body
+	}
+
+	// Header values are rendered one by one, so they need no JSON escaping
+	if raw, ok := entry["headers"]; ok && strings.Contains(raw, "{{") {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			return fmt.Errorf("invalid 'headers' in config: %w", err)
+		}
+		for name, value := range headers {
+			if headers[name], err = render("headers", value); err != nil {
+				return err
+			}
+		}
+		encoded, _ := json.Marshal(headers)
+		entry["headers"] = string(encoded)
+	}
+	return nil
+}
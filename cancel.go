@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ---- Cancel Token Endpoint ----
+
+// defaultCancelPollInterval is how often cancelUrl is checked when
+// 'cancelPollInterval' is not set.
+const defaultCancelPollInterval = 5 * time.Second
+
+// errCancelled is the cancellation cause recorded when cancelUrl asks the
+// probe to stop.
+type errCancelled struct {
+	reason string
+}
+
+func (e *errCancelled) Error() string {
+	return fmt.Sprintf("probe cancelled via cancelUrl: %s", e.reason)
+}
+
+// watchCancelURL polls cancelURL every interval until ctx is done. A 200
+// response lets the probe continue; any other status, or a failure to reach
+// the endpoint, cancels ctx through cancel. Each poll is a single GET whose
+// body is discarded, bounded by the poll interval itself.
+func watchCancelURL(ctx context.Context, cancelURL string, interval time.Duration, cancel context.CancelCauseFunc) {
+	client := &http.Client{Timeout: interval}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cancelURL, nil)
+		if err != nil {
+			cancel(&errCancelled{reason: err.Error()})
+			return
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() == nil {
+				cancel(&errCancelled{reason: err.Error()})
+			}
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			cancel(&errCancelled{reason: resp.Status})
+			return
+		}
+	}
+}
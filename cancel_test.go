@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancelURLAbortsProbe(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	stop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer stop.Close()
+
+	start := time.Now()
+	output, err := runPlugin(t, map[string]string{
+		"uri":                slow.URL,
+		"method":             "GET",
+		"cancelUrl":          stop.URL,
+		"cancelPollInterval": "20ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success {
+		t.Fatal("Expected cancelled probe to fail")
+	}
+	if !strings.Contains(output.Message, "cancelled via cancelUrl: 410 Gone") {
+		t.Errorf("Expected cancellation reason in message, got: %v", output.Message)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Probe was not cancelled promptly (took %v)", elapsed)
+	}
+}
+
+func TestCancelURLContinues(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer target.Close()
+
+	keepGoing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer keepGoing.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":                target.URL,
+		"method":             "GET",
+		"cancelUrl":          keepGoing.URL,
+		"cancelPollInterval": "10ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected successful response, got: %v", output.Message)
+	}
+}
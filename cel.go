@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// ---- CEL Expressions ----
+
+// celGate is a compiled 'celExpression'. It is evaluated against:
+//
+//	status   int                  the response status code
+//	headers  map(string, string)  response headers, names in lower case
+//	body     dyn                  the body parsed as JSON, or the raw string
+//	duration double               the request duration in milliseconds
+type celGate struct {
+	expr    string
+	program cel.Program
+}
+
+func compileCEL(expr string) (*celGate, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("status", cel.IntType),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("body", cel.DynType),
+		cel.Variable("duration", cel.DoubleType),
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up 'celExpression': %w", err)
+	}
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid 'celExpression': %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("invalid 'celExpression': must evaluate to a bool, not %s", ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'celExpression': %w", err)
+	}
+	return &celGate{expr: expr, program: program}, nil
+}
+
+// criterion evaluates the expression for a response. It passes only when
+// the expression returns true.
+func (g *celGate) criterion(resp *http.Response, body []byte, duration time.Duration) criterion {
+	cr := criterion{name: "celExpression"}
+
+	headers := make(map[string]string, len(resp.Header))
+	for name, values := range resp.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		parsed = string(body)
+	}
+
+	out, _, err := g.program.Eval(map[string]interface{}{
+		"status":   resp.StatusCode,
+		"headers":  headers,
+		"body":     parsed,
+		"duration": milliseconds(duration),
+	})
+	if err != nil {
+		cr.detail = fmt.Sprintf("evaluation failed: %v", err)
+		return cr
+	}
+	passed, ok := out.(types.Bool)
+	if !ok {
+		cr.detail = fmt.Sprintf("evaluated to %v (%s), not a bool", out.Value(), out.Type().TypeName())
+		return cr
+	}
+	cr.passed = bool(passed)
+	cr.detail = fmt.Sprintf("%s is %t", g.expr, cr.passed)
+	return cr
+}
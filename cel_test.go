@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCELExpression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("gone"))
+		default:
+			w.Header().Set("X-Version", "v2")
+			w.Write([]byte(`{"ready": true, "replicas": 3, "zones": ["a", "b"]}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		expr        string
+		wantSuccess bool
+		wantDetail  string
+	}{
+		{name: "json body", expr: "status == 200 && body.ready == true && body.replicas >= 3 && duration < 5000", wantSuccess: true},
+		{name: "headers", expr: `headers["x-version"] == "v2" && size(body.zones) == 2`, wantSuccess: true},
+		{name: "false", expr: "body.replicas > 3", wantDetail: "[FAIL] celExpression: body.replicas > 3 is false"},
+		{name: "decides on the status", path: "/missing", expr: `status == 404 && body == "gone"`, wantSuccess: true},
+		{name: "evaluation error", expr: "body.missing == 1", wantDetail: "evaluation failed: no such key: missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": server.URL + tt.path, "method": "GET", "celExpression": tt.expr})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantDetail) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestCELCompileErrors(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	for _, expr := range []string{
+		"status ==",
+		"unknown_var == 1",
+		"status + 1",
+	} {
+		_, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "celExpression": expr})
+		if err == nil || !strings.Contains(err.Error(), "invalid 'celExpression'") {
+			t.Errorf("celExpression %q: error = %v, want a compile error", expr, err)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Errorf("Expected no request to be made, got %d", hits.Load())
+	}
+}
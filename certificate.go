@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ---- Certificate Assertions ----
+
+// TLSInfo describes the certificate the server presented.
+type TLSInfo struct {
+	Version      string    `json:"version"`
+	Subject      string    `json:"subject"`
+	CommonName   string    `json:"commonName,omitempty"`
+	Issuer       string    `json:"issuer"`
+	SANs         []string  `json:"sans,omitempty"`
+	NotAfter     time.Time `json:"notAfter"`
+	DaysToExpiry int       `json:"daysToExpiry"`
+}
+
+// newTLSInfo describes the leaf certificate of state.
+func newTLSInfo(state *tls.ConnectionState, now time.Time) *TLSInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+	info := &TLSInfo{
+		Version:      tls.VersionName(state.Version),
+		Subject:      cert.Subject.String(),
+		CommonName:   cert.Subject.CommonName,
+		Issuer:       cert.Issuer.String(),
+		NotAfter:     cert.NotAfter,
+		DaysToExpiry: int(cert.NotAfter.Sub(now).Hours() / 24),
+	}
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	return info
+}
+
+// tlsAssertions are the certificate checks from 'tlsAssertions'.
+type tlsAssertions struct {
+	ExpectedCN      string `json:"expectedCN"`
+	ExpectedSAN     string `json:"expectedSAN"`
+	MinDaysToExpiry *int   `json:"minDaysToExpiry"`
+}
+
+func parseTLSAssertions(config map[string]string) (*tlsAssertions, error) {
+	raw, ok := config["tlsAssertions"]
+	if !ok {
+		return nil, nil
+	}
+	var assertions tlsAssertions
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&assertions); err != nil {
+		return nil, fmt.Errorf("invalid 'tlsAssertions' in config: %w", err)
+	}
+	return &assertions, nil
+}
+
+// criterion checks info against the assertions, listing every failure.
+func (a *tlsAssertions) criterion(info *TLSInfo) criterion {
+	cr := criterion{name: "tlsAssertions"}
+	if info == nil {
+		cr.detail = "no TLS certificate was presented"
+		return cr
+	}
+
+	var failures []string
+	if a.ExpectedCN != "" && info.CommonName != a.ExpectedCN {
+		failures = append(failures, fmt.Sprintf("common name %q (expected %q)", info.CommonName, a.ExpectedCN))
+	}
+	if a.ExpectedSAN != "" && !slices.Contains(info.SANs, a.ExpectedSAN) {
+		failures = append(failures, fmt.Sprintf("SAN %q not in %v", a.ExpectedSAN, info.SANs))
+	}
+	if a.MinDaysToExpiry != nil && info.DaysToExpiry < *a.MinDaysToExpiry {
+		failures = append(failures, fmt.Sprintf("expires in %d days (minimum %d)", info.DaysToExpiry, *a.MinDaysToExpiry))
+	}
+	if len(failures) > 0 {
+		cr.detail = strings.Join(failures, "; ")
+		return cr
+	}
+	cr.passed = true
+	cr.detail = fmt.Sprintf("%s, expires in %d days", info.Subject, info.DaysToExpiry)
+	return cr
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSAssertions(t *testing.T) {
+	now := time.Now()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "canary.example.com", Organization: []string{"Naviteq"}},
+		DNSNames:     []string{"canary.example.com", "api.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.7")},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(20*24*time.Hour + time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	info := newTLSInfo(&tls.ConnectionState{Version: tls.VersionTLS13, PeerCertificates: []*x509.Certificate{cert}}, now)
+	if info.Version != "TLS 1.3" || info.CommonName != "canary.example.com" || info.DaysToExpiry != 20 {
+		t.Errorf("Unexpected TLS info: %+v", info)
+	}
+	if strings.Join(info.SANs, ",") != "canary.example.com,api.example.com,10.0.0.7" {
+		t.Errorf("SANs = %v", info.SANs)
+	}
+
+	tests := []struct {
+		assertions  string
+		wantPassed  bool
+		wantDetails []string
+	}{
+		{assertions: `{"expectedCN": "canary.example.com", "expectedSAN": "api.example.com", "minDaysToExpiry": 14}`, wantPassed: true},
+		{assertions: `{"expectedSAN": "10.0.0.7"}`, wantPassed: true},
+		{assertions: `{"expectedCN": "stable.example.com"}`, wantDetails: []string{`common name "canary.example.com" (expected "stable.example.com")`}},
+		{assertions: `{"expectedSAN": "www.example.com", "minDaysToExpiry": 30}`, wantDetails: []string{`SAN "www.example.com" not in`, "expires in 20 days (minimum 30)"}},
+	}
+	for _, tt := range tests {
+		assertions, err := parseTLSAssertions(map[string]string{"tlsAssertions": tt.assertions})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cr := assertions.criterion(info)
+		if cr.passed != tt.wantPassed {
+			t.Errorf("%s: passed = %v, want %v (%s)", tt.assertions, cr.passed, tt.wantPassed, cr.detail)
+		}
+		for _, want := range tt.wantDetails {
+			if !strings.Contains(cr.detail, want) {
+				t.Errorf("%s: detail = %q, want it to contain %q", tt.assertions, cr.detail, want)
+			}
+		}
+	}
+}
+
+func TestTLSAssertionsWithoutTLS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "tlsAssertions": `{"minDaysToExpiry": 1}`})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || output.TLS != nil || !strings.Contains(output.Message, "no TLS certificate was presented") {
+		t.Errorf("Expected the assertion to fail over plain http, got: %v", output.Message)
+	}
+
+	for _, raw := range []string{`{"expectedIssuer": "x"}`, `["x"]`, `{"minDaysToExpiry": "30"}`} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "tlsAssertions": raw}); err == nil {
+			t.Errorf("Expected error for tlsAssertions %s", raw)
+		}
+	}
+}
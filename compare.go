@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ---- Response Comparison ----
+
+// volatileHeaders differ between any two responses, so they are never
+// compared.
+var volatileHeaders = []string{"Date", "Age", "Expires", "Set-Cookie", "Content-Length"}
+
+// runCompare probes 'uri' and 'compareUri' with the same settings and
+// passes when both probes pass and their responses are identical: same
+// status, headers and body, apart from 'compareIgnoreHeaders' and the
+// JSON fields in 'compareIgnoreFields'.
+func (p *HTTPPlugin) runCompare(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	if _, ok := config["requests"]; ok {
+		return PluginOutput{}, fmt.Errorf("'compareUri' cannot be combined with 'requests'")
+	}
+	mode, err := parseOutputMode(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	ignoreHeaders := append(configList(config, "compareIgnoreHeaders"), volatileHeaders...)
+	ignoreFields := configList(config, "compareIgnoreFields")
+
+	primary := make(map[string]string, len(config))
+	for key, value := range config {
+		if key != "compareUri" && key != "compareIgnoreHeaders" && key != "compareIgnoreFields" {
+			primary[key] = value
+		}
+	}
+	output, err := p.runEntry(ctx, primary)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	other := make(map[string]string, len(primary))
+	for key, value := range primary {
+		other[key] = value
+	}
+	other["uri"] = config["compareUri"]
+	compared, err := p.runEntry(ctx, other)
+	if err != nil {
+		return PluginOutput{}, fmt.Errorf("compareUri: %w", err)
+	}
+
+	var diff string
+	switch {
+	case output.status == 0 || compared.status == 0:
+		diff = "no response to compare"
+	case output.status != compared.status:
+		diff = fmt.Sprintf("status %d vs %d", output.status, compared.status)
+	default:
+		if diff = headerDiff(output.header, compared.header, ignoreHeaders); diff == "" {
+			diff = bodyDiff(output.body, compared.body, ignoreFields)
+		}
+	}
+
+	result := output
+	result.Success = output.Success && compared.Success && diff == ""
+	result.Diff = diff
+	if !result.Success && result.FailureReason == "" {
+		switch {
+		case !output.Success:
+			result.FailureReason = "uri probe failed"
+		case !compared.Success:
+			result.FailureReason = "compareUri probe failed"
+			if compared.FailureReason != "" {
+				result.FailureReason += ": " + compared.FailureReason
+			}
+		default:
+			result.FailureReason = "responses differ: " + diff
+		}
+	}
+	if mode != outputModeMinimal {
+		verdict := "identical"
+		if diff != "" {
+			verdict = "differ, " + diff
+		}
+		result.Message = fmt.Sprintf("%s\n\ncompareUri %s:\n%s\n\nResponses %s", output.Message, other["uri"], compared.Message, verdict)
+	}
+	return result, nil
+}
+
+// headerDiff describes the first header, in name order, whose values
+// differ, or returns "" when they all match.
+func headerDiff(a, b http.Header, ignore []string) string {
+	skip := make(map[string]bool, len(ignore))
+	for _, name := range ignore {
+		skip[http.CanonicalHeaderKey(name)] = true
+	}
+	names := make(map[string]bool)
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if !skip[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		av, bv := strings.Join(a.Values(name), ", "), strings.Join(b.Values(name), ", ")
+		if av != bv {
+			return fmt.Sprintf("header %s: %q vs %q", name, av, bv)
+		}
+	}
+	return ""
+}
+
+// bodyDiff describes the first difference between two bodies, or returns
+// "" when they match. JSON bodies are compared as documents, without the
+// ignored fields; anything else byte for byte.
+func bodyDiff(a, b []byte, ignoreFields []string) string {
+	var adoc, bdoc interface{}
+	if json.Unmarshal(a, &adoc) == nil && json.Unmarshal(b, &bdoc) == nil {
+		for _, path := range ignoreFields {
+			removeJSONField(adoc, path)
+			removeJSONField(bdoc, path)
+		}
+		return jsonDiff("body", adoc, bdoc)
+	}
+
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return fmt.Sprintf("body differs at byte %d: %q vs %q", i, excerpt(a, i), excerpt(b, i))
+}
+
+// jsonDiff returns the first difference between two decoded JSON values,
+// naming its path, or "" when they are equal.
+func jsonDiff(path string, a, b interface{}) string {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for key := range av {
+			keys[key] = true
+		}
+		for key := range bv {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			x, inA := av[key]
+			y, inB := bv[key]
+			switch {
+			case !inA:
+				return fmt.Sprintf("%s.%s: missing vs %s", path, key, jsonValueString(y))
+			case !inB:
+				return fmt.Sprintf("%s.%s: %s vs missing", path, key, jsonValueString(x))
+			}
+			if d := jsonDiff(path+"."+key, x, y); d != "" {
+				return d
+			}
+		}
+		return ""
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if d := jsonDiff(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i]); d != "" {
+				return d
+			}
+		}
+		if len(av) != len(bv) {
+			return fmt.Sprintf("%s: length %d vs %d", path, len(av), len(bv))
+		}
+		return ""
+	}
+	if reflect.DeepEqual(a, b) {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s vs %s", path, jsonValueString(a), jsonValueString(b))
+}
+
+// removeJSONField deletes the object field at path, such as
+// "meta.generatedAt", from doc. Paths that do not resolve are ignored.
+func removeJSONField(doc interface{}, path string) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	parent, name := "", path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		parent, name = path[:i], path[i+1:]
+	}
+	container, err := evalJSONPath(doc, parent)
+	if err != nil {
+		return
+	}
+	if obj, ok := container.(map[string]interface{}); ok {
+		delete(obj, name)
+	}
+}
+
+// excerpt returns up to 20 bytes of body from offset i.
+func excerpt(body []byte, i int) string {
+	end := min(i+20, len(body))
+	return string(body[i:end])
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareUri(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Version", "v1")
+		switch r.URL.Path {
+		case "/stable", "/canary":
+			w.Header().Set("X-Served-By", strings.TrimPrefix(r.URL.Path, "/"))
+			w.Write([]byte(`{"items": [1, 2], "meta": {"generatedAt": "` + r.URL.Path + `"}}`))
+		case "/changed":
+			w.Write([]byte(`{"items": [1, 3], "meta": {"generatedAt": "now"}}`))
+		case "/v2":
+			w.Header().Set("X-Version", "v2")
+			w.Write([]byte(`{"items": [1, 2]}`))
+		case "/text-a":
+			w.Write([]byte("hello world"))
+		case "/text-b":
+			w.Write([]byte("hello there"))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ignore := map[string]string{"compareIgnoreHeaders": "X-Served-By", "compareIgnoreFields": "meta.generatedAt"}
+	tests := []struct {
+		name        string
+		uri         string
+		compare     string
+		config      map[string]string
+		wantSuccess bool
+		wantDiff    string
+	}{
+		{name: "identical after ignoring", uri: "/stable", compare: "/canary", config: ignore, wantSuccess: true},
+		{name: "ignored header compared", uri: "/stable", compare: "/canary", config: map[string]string{"compareIgnoreFields": "meta.generatedAt"}, wantDiff: `header X-Served-By: "stable" vs "canary"`},
+		{name: "ignored field compared", uri: "/stable", compare: "/canary", config: map[string]string{"compareIgnoreHeaders": "X-Served-By"}, wantDiff: `body.meta.generatedAt: /stable vs /canary`},
+		{name: "json difference", uri: "/stable", compare: "/changed", config: ignore, wantDiff: "body.items[1]: 2 vs 3"},
+		{name: "header difference", uri: "/stable", compare: "/v2", config: ignore, wantDiff: `header X-Version: "v1" vs "v2"`},
+		{name: "text difference", uri: "/text-a", compare: "/text-b", wantDiff: `body differs at byte 6: "world" vs "there"`},
+		{name: "status difference", uri: "/stable", compare: "/missing", wantDiff: "status 200 vs 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.uri, "compareUri": server.URL + tt.compare, "method": "GET"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if output.Diff != tt.wantDiff {
+				t.Errorf("Diff = %q, want %q", output.Diff, tt.wantDiff)
+			}
+			if tt.wantDiff != "" && !strings.Contains(output.Message, "Responses differ, "+tt.wantDiff) {
+				t.Errorf("Message = %q, want it to report the difference", output.Message)
+			}
+		})
+	}
+
+	// Identical failures still fail the step
+	output, err := runPlugin(t, map[string]string{"uri": server.URL + "/missing", "compareUri": server.URL + "/missing", "method": "GET"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || output.Diff != "" || output.FailureReason != "uri probe failed" {
+		t.Errorf("Expected identical failing probes to fail, got %+v", output)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "compareUri": server.URL, "method": "GET", "requests": `[{}]`}); err == nil {
+		t.Error("Expected error for 'compareUri' with 'requests'")
+	}
+}
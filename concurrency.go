@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ---- Concurrency Guard ----
+
+// maxConcurrentRunsEnv names the environment variable capping how many Run
+// calls may probe at the same time. Unlimited when unset.
+const maxConcurrentRunsEnv = "PLUGIN_MAX_CONCURRENT_RUNS"
+
+// runSlots is a process-wide semaphore; nil means unlimited.
+var runSlots chan struct{}
+
+// newRunSlotsFromEnv builds the semaphore described by the environment,
+// returning nil when no limit is configured.
+func newRunSlotsFromEnv() (chan struct{}, error) {
+	raw := os.Getenv(maxConcurrentRunsEnv)
+	if raw == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid %s %q: expected a positive integer", maxConcurrentRunsEnv, raw)
+	}
+	return make(chan struct{}, n), nil
+}
+
+// acquireRunSlot blocks until a slot is free or ctx is done. The returned
+// function releases the slot.
+func acquireRunSlot(ctx context.Context) (func(), error) {
+	if runSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case runSlots <- struct{}{}:
+		return func() { <-runSlots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a free run slot: %w", ctx.Err())
+	}
+}
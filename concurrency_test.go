@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunSlotsFromEnv(t *testing.T) {
+	t.Setenv(maxConcurrentRunsEnv, "")
+	if slots, err := newRunSlotsFromEnv(); err != nil || slots != nil {
+		t.Errorf("Expected unlimited runs when unset, got %v, %v", slots, err)
+	}
+
+	t.Setenv(maxConcurrentRunsEnv, "3")
+	if slots, err := newRunSlotsFromEnv(); err != nil || cap(slots) != 3 {
+		t.Errorf("Expected 3 slots, got %v, %v", slots, err)
+	}
+
+	for _, raw := range []string{"0", "-2", "many"} {
+		t.Setenv(maxConcurrentRunsEnv, raw)
+		if _, err := newRunSlotsFromEnv(); err == nil {
+			t.Errorf("Expected error for %s=%q", maxConcurrentRunsEnv, raw)
+		}
+	}
+}
+
+func TestRunSlotsLimitConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	runSlots = make(chan struct{}, 2)
+	defer func() { runSlots = nil }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"})
+			if err != nil || !output.Success {
+				t.Errorf("Expected success: %v %v", err, output.Message)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("Peak concurrency = %d, want at most 2", got)
+	}
+}
+
+func TestRunSlotWaitHonorsContext(t *testing.T) {
+	runSlots = make(chan struct{}, 1)
+	defer func() { runSlots = nil }()
+
+	release, err := acquireRunSlot(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquireRunSlot(ctx); err == nil {
+		t.Error("Expected error when no slot frees up before the deadline")
+	}
+}
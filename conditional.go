@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ---- Conditional Requests ----
+
+// isConditional reports whether config turns the probe into a cache
+// validation request.
+func isConditional(config map[string]string) bool {
+	return config["ifNoneMatch"] != "" || config["ifModifiedSince"] != ""
+}
+
+// applyConditionalHeaders sets If-None-Match and If-Modified-Since. The
+// latter accepts either an HTTP date or an RFC 3339 timestamp.
+func applyConditionalHeaders(req *http.Request, config map[string]string) error {
+	if etag := config["ifNoneMatch"]; etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if since := config["ifModifiedSince"]; since != "" {
+		t, err := http.ParseTime(since)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, since); err != nil {
+				return fmt.Errorf("invalid 'ifModifiedSince' %q: expected an HTTP date or RFC 3339 timestamp", since)
+			}
+		}
+		req.Header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+	}
+	return nil
+}
+
+// describeConditional explains the outcome of a conditional request so a
+// 304 is clearly distinguishable from a full response.
+func describeConditional(resp *http.Response) string {
+	if resp.StatusCode == http.StatusNotModified {
+		return "Conditional: not modified, cached copy is current"
+	}
+	return "Conditional: modified, full response returned"
+}
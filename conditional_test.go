@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "asset.txt", lastModified, strings.NewReader("asset body"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		config      map[string]string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{
+			name:        "matching etag",
+			config:      map[string]string{"ifNoneMatch": `"v2"`},
+			wantSuccess: true,
+			wantMessage: "Conditional: not modified",
+		},
+		{
+			name:        "stale etag",
+			config:      map[string]string{"ifNoneMatch": `"v1"`},
+			wantSuccess: true,
+			wantMessage: "Conditional: modified",
+		},
+		{
+			name:        "not modified since",
+			config:      map[string]string{"ifModifiedSince": "2024-06-01T00:00:00Z"},
+			wantSuccess: true,
+			wantMessage: "Conditional: not modified",
+		},
+		{
+			name:        "modified since (http date)",
+			config:      map[string]string{"ifModifiedSince": "Mon, 01 Jan 2024 00:00:00 GMT"},
+			wantSuccess: true,
+			wantMessage: "Conditional: modified",
+		},
+		{
+			name:        "304 rejected when not accepted",
+			config:      map[string]string{"ifNoneMatch": `"v2"`, "acceptNotModified": "false"},
+			wantSuccess: false,
+			wantMessage: "Status: 304 Not Modified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL, "method": "GET"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v", output.Success, tt.wantSuccess)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "ifModifiedSince": "yesterday"}); err == nil {
+		t.Error("Expected error for invalid ifModifiedSince")
+	}
+}
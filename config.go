@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---- Config Helpers ----
+
+// configInt returns the integer value of key, or def when the key is absent.
+func configInt(config map[string]string, key string, def int) (int, error) {
+	raw, ok := config[key]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid '%s' in config: %w", key, err)
+	}
+	return v, nil
+}
+
+// configDuration returns the duration value of key, or def when the key is absent.
+func configDuration(config map[string]string, key string, def time.Duration) (time.Duration, error) {
+	raw, ok := config[key]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return def, nil
+	}
+	v, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid '%s' in config: %w", key, err)
+	}
+	return v, nil
+}
+
+// configFloat returns the floating-point value of key, or def when the key is absent.
+func configFloat(config map[string]string, key string, def float64) (float64, error) {
+	raw, ok := config[key]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid '%s' in config: %w", key, err)
+	}
+	return v, nil
+}
+
+// configBool returns the boolean value of key, or def when the key is absent.
+func configBool(config map[string]string, key string, def bool) (bool, error) {
+	raw, ok := config[key]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		return false, fmt.Errorf("invalid '%s' in config: %w", key, err)
+	}
+	return v, nil
+}
+
+// configList splits a comma-separated value into trimmed, non-empty items.
+func configList(config map[string]string, key string) []string {
+	var items []string
+	for _, item := range strings.Split(config[key], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// configOrEnv returns the value of key, falling back to the environment
+// variable env when the key is absent or empty.
+func configOrEnv(config map[string]string, key, env string) string {
+	if v := config[key]; v != "" {
+		return v
+	}
+	return os.Getenv(env)
+}
+
+// firstKey returns the first of keys present in config, or "" if none is.
+func firstKey(config map[string]string, keys []string) string {
+	for _, key := range keys {
+		if _, ok := config[key]; ok {
+			return key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDefaultURIAndMethodFromEnv(t *testing.T) {
+	var gotMethod string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+	}))
+	defer server.Close()
+
+	t.Setenv("PLUGIN_DEFAULT_URI", server.URL)
+	t.Setenv("PLUGIN_DEFAULT_METHOD", "HEAD")
+
+	output, err := runPlugin(t, map[string]string{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || gotMethod != "HEAD" {
+		t.Errorf("Expected HEAD request from env defaults, got method %q: %v", gotMethod, output.Message)
+	}
+
+	// Config values take precedence over the environment
+	if _, err := runPlugin(t, map[string]string{"method": "GET"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotMethod != "GET" {
+		t.Errorf("Expected config method to win, got %q", gotMethod)
+	}
+}
+
+func TestMissingURIWithoutEnv(t *testing.T) {
+	t.Setenv("PLUGIN_DEFAULT_URI", "")
+	t.Setenv("PLUGIN_DEFAULT_METHOD", "GET")
+
+	if _, err := runPlugin(t, map[string]string{}); err == nil {
+		t.Error("Expected error when uri is missing from both config and env")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ---- Config File ----
+
+// mergeConfigFile returns config with the keys of 'configFile' (JSON or
+// YAML) added as defaults: keys set inline take precedence. Structured
+// values, such as a headers object, are passed on as JSON text.
+func mergeConfigFile(config map[string]string) (map[string]string, error) {
+	path := config["configFile"]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 'configFile': %w", err)
+	}
+
+	// JSON is valid YAML, so one decoder handles both formats
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse configFile %s: %w", path, err)
+	}
+
+	merged := make(map[string]string, len(values)+len(config))
+	for key, value := range values {
+		if key == "configFile" {
+			return nil, fmt.Errorf("configFile %s: 'configFile' cannot be nested", path)
+		}
+		s, err := configFileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("configFile %s: invalid value for '%s': %w", path, key, err)
+		}
+		merged[key] = s
+	}
+	for key, value := range config {
+		if key != "configFile" {
+			merged[key] = value
+		}
+	}
+	return merged, nil
+}
+
+func configFileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("X-Team")))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	yamlFile := write("probe.yaml", `
+uri: `+server.URL+`/from-file
+method: POST
+retries: 1
+headers:
+  X-Team: canary
+`)
+	jsonFile := write("probe.json", `{"uri": "`+server.URL+`/json", "method": "PUT", "headers": {"X-Team": "json"}}`)
+
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   string
+	}{
+		{name: "yaml defaults", config: map[string]string{"configFile": yamlFile}, want: "POST /from-file canary"},
+		{name: "inline overrides", config: map[string]string{"configFile": yamlFile, "method": "GET", "headers": `{"X-Team": "inline"}`}, want: "GET /from-file inline"},
+		{name: "json file", config: map[string]string{"configFile": jsonFile}, want: "PUT /json json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !output.Success || !strings.HasSuffix(output.Message, "Body: "+tt.want) {
+				t.Errorf("Message = %q, want body %q", output.Message, tt.want)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		path    string
+		wantErr string
+	}{
+		{path: filepath.Join(dir, "missing.yaml"), wantErr: "failed to read 'configFile'"},
+		{path: write("broken.yaml", "uri: [unclosed\n"), wantErr: "failed to parse configFile " + filepath.Join(dir, "broken.yaml")},
+		{path: write("list.yaml", "- uri\n"), wantErr: "failed to parse configFile"},
+		{path: write("keys.yaml", "headers:\n  true: one\n"), wantErr: "invalid value for 'headers'"},
+		{path: write("nested.yaml", "configFile: other.yaml\n"), wantErr: "cannot be nested"},
+	}
+	for _, tt := range errorTests {
+		_, err := runPlugin(t, map[string]string{"configFile": tt.path})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.path, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ---- Consecutive Successes ----
+
+const defaultConsecutiveInterval = time.Second
+
+// runConsecutive repeats the probe described by the rest of config until
+// 'requiredConsecutiveSuccesses' attempts in a row pass. A failed attempt
+// resets the streak; the run gives up once 'consecutiveMaxAttempts' is
+// spent or the streak can no longer be reached within it.
+func (p *HTTPPlugin) runConsecutive(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	required, err := configInt(config, "requiredConsecutiveSuccesses", 1)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if required < 1 {
+		return PluginOutput{}, fmt.Errorf("invalid 'requiredConsecutiveSuccesses' in config: must be at least 1")
+	}
+	maxAttempts, err := configInt(config, "consecutiveMaxAttempts", 2*required)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if maxAttempts < required {
+		return PluginOutput{}, fmt.Errorf("invalid 'consecutiveMaxAttempts' in config: must be at least 'requiredConsecutiveSuccesses'")
+	}
+	interval, err := configDuration(config, "consecutiveInterval", defaultConsecutiveInterval)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	mode, err := parseOutputMode(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	probe := make(map[string]string, len(config))
+	for key, value := range config {
+		switch key {
+		case "requiredConsecutiveSuccesses", "consecutiveMaxAttempts", "consecutiveInterval":
+		default:
+			probe[key] = value
+		}
+	}
+
+	var output PluginOutput
+	streak, attempts := 0, 0
+	for streak < required && maxAttempts-attempts >= required-streak {
+		if attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return errorOutput(mode, context.Cause(ctx)), nil
+			case <-time.After(interval):
+			}
+		}
+		if output, err = p.runEntry(ctx, probe); err != nil {
+			return PluginOutput{}, err
+		}
+		attempts++
+		if output.Success {
+			streak++
+		} else {
+			streak = 0
+		}
+	}
+
+	output.Success = streak >= required
+	output.Streak, output.Attempts = streak, attempts
+	if mode != outputModeMinimal {
+		output.Message += fmt.Sprintf("\nConsecutive successes: %d/%d after %d attempt(s)", streak, required, attempts)
+	}
+	if !output.Success && output.FailureReason == "" {
+		output.FailureReason = fmt.Sprintf("%d consecutive successes required, reached %d within %d attempt(s)", required, streak, attempts)
+	}
+	return output, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRequiredConsecutiveSuccesses(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     string // one character per request: 'o' ok, 'x' error
+		config       map[string]string
+		wantSuccess  bool
+		wantStreak   int
+		wantAttempts int
+	}{
+		{name: "straight passes", statuses: "ooo", config: map[string]string{"requiredConsecutiveSuccesses": "3"}, wantSuccess: true, wantStreak: 3, wantAttempts: 3},
+		{name: "failure resets streak", statuses: "oxooo", config: map[string]string{"requiredConsecutiveSuccesses": "3"}, wantSuccess: true, wantStreak: 3, wantAttempts: 5},
+		{name: "gives up when out of attempts", statuses: "ooxoxo", config: map[string]string{"requiredConsecutiveSuccesses": "3", "consecutiveMaxAttempts": "6"}, wantSuccess: false, wantStreak: 0, wantAttempts: 5},
+		{name: "default budget", statuses: "xxxxxx", config: map[string]string{"requiredConsecutiveSuccesses": "2"}, wantSuccess: false, wantStreak: 0, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(hits.Add(1)) - 1
+				if n >= len(tt.statuses) || tt.statuses[n] == 'x' {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			config := map[string]string{"uri": server.URL, "method": "GET", "consecutiveInterval": "1ms"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess || output.Streak != tt.wantStreak || output.Attempts != tt.wantAttempts {
+				t.Errorf("got success=%v streak=%d attempts=%d, want %v/%d/%d (message: %s)",
+					output.Success, output.Streak, output.Attempts, tt.wantSuccess, tt.wantStreak, tt.wantAttempts, output.Message)
+			}
+			if int(hits.Load()) != tt.wantAttempts {
+				t.Errorf("server saw %d requests, want %d", hits.Load(), tt.wantAttempts)
+			}
+			if !strings.Contains(output.Message, "Consecutive successes: ") {
+				t.Errorf("Message = %q, want the streak", output.Message)
+			}
+		})
+	}
+}
+
+func TestRequiredConsecutiveSuccessesInvalid(t *testing.T) {
+	for _, config := range []map[string]string{
+		{"requiredConsecutiveSuccesses": "0"},
+		{"requiredConsecutiveSuccesses": "three"},
+		{"requiredConsecutiveSuccesses": "3", "consecutiveMaxAttempts": "2"},
+		{"requiredConsecutiveSuccesses": "3", "consecutiveInterval": "soon"},
+	} {
+		config["uri"] = "http://127.0.0.1"
+		config["method"] = "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
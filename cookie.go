@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ---- Cookie Assertions ----
+
+// CookieInfo describes the attributes of a cookie set by the response.
+type CookieInfo struct {
+	Name     string `json:"name"`
+	Secure   bool   `json:"secure"`
+	HttpOnly bool   `json:"httpOnly"`
+	SameSite string `json:"sameSite,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+}
+
+// sameSiteNames maps the SameSite modes to their attribute values. A
+// SameSite attribute without a recognised value reads as "Default".
+var sameSiteNames = map[http.SameSite]string{
+	http.SameSiteDefaultMode: "Default",
+	http.SameSiteLaxMode:     "Lax",
+	http.SameSiteStrictMode:  "Strict",
+	http.SameSiteNoneMode:    "None",
+}
+
+func newCookieInfo(c *http.Cookie) CookieInfo {
+	return CookieInfo{
+		Name:     c.Name,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		SameSite: sameSiteNames[c.SameSite],
+		Path:     c.Path,
+		Domain:   c.Domain,
+	}
+}
+
+// cookieRequirement holds the attributes required of one cookie; unset
+// fields are not checked.
+type cookieRequirement struct {
+	Secure   *bool   `json:"secure"`
+	HttpOnly *bool   `json:"httpOnly"`
+	SameSite *string `json:"sameSite"`
+	Path     *string `json:"path"`
+	Domain   *string `json:"domain"`
+}
+
+// cookieAssertions are the checks from 'cookieAssertions', by cookie name.
+type cookieAssertions map[string]cookieRequirement
+
+func parseCookieAssertions(config map[string]string) (cookieAssertions, error) {
+	raw, ok := config["cookieAssertions"]
+	if !ok {
+		return nil, nil
+	}
+	var assertions cookieAssertions
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&assertions); err != nil {
+		return nil, fmt.Errorf("invalid 'cookieAssertions' in config: %w", err)
+	}
+	if len(assertions) == 0 {
+		return nil, fmt.Errorf("invalid 'cookieAssertions' in config: expected at least one cookie")
+	}
+	return assertions, nil
+}
+
+// cookies describes the asserted cookies the response sets, in name order.
+// When a cookie is set more than once the last one wins, as in a browser.
+func (a cookieAssertions) cookies(resp *http.Response) []CookieInfo {
+	last := make(map[string]*http.Cookie)
+	for _, c := range resp.Cookies() {
+		if _, ok := a[c.Name]; ok {
+			last[c.Name] = c
+		}
+	}
+	infos := make([]CookieInfo, 0, len(last))
+	for _, c := range last {
+		infos = append(infos, newCookieInfo(c))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// criterion checks the cookies against the assertions, listing every
+// failure.
+func (a cookieAssertions) criterion(cookies []CookieInfo) criterion {
+	cr := criterion{name: "cookieAssertions"}
+	found := make(map[string]CookieInfo, len(cookies))
+	for _, c := range cookies {
+		found[c.Name] = c
+	}
+
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failures []string
+	for _, name := range names {
+		want := a[name]
+		got, ok := found[name]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("cookie %s is not set", name))
+			continue
+		}
+		if want.Secure != nil && got.Secure != *want.Secure {
+			failures = append(failures, fmt.Sprintf("cookie %s has Secure=%t (expected %t)", name, got.Secure, *want.Secure))
+		}
+		if want.HttpOnly != nil && got.HttpOnly != *want.HttpOnly {
+			failures = append(failures, fmt.Sprintf("cookie %s has HttpOnly=%t (expected %t)", name, got.HttpOnly, *want.HttpOnly))
+		}
+		if want.SameSite != nil && !strings.EqualFold(got.SameSite, *want.SameSite) {
+			failures = append(failures, fmt.Sprintf("cookie %s has SameSite=%q (expected %q)", name, got.SameSite, *want.SameSite))
+		}
+		if want.Path != nil && got.Path != *want.Path {
+			failures = append(failures, fmt.Sprintf("cookie %s has Path=%q (expected %q)", name, got.Path, *want.Path))
+		}
+		if want.Domain != nil && !strings.EqualFold(got.Domain, *want.Domain) {
+			failures = append(failures, fmt.Sprintf("cookie %s has Domain=%q (expected %q)", name, got.Domain, *want.Domain))
+		}
+	}
+	if len(failures) > 0 {
+		cr.detail = strings.Join(failures, "; ")
+		return cr
+	}
+	cr.passed = true
+	cr.detail = fmt.Sprintf("%d cookie(s) as expected", len(names))
+	return cr
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCookieAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s", Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		if r.URL.Path == "/regressed" {
+			// A later cookie of the same name replaces the first
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s", Path: "/", SameSite: http.SameSiteLaxMode})
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		assertions  string
+		wantSuccess bool
+		wantDetail  string
+	}{
+		{name: "secure cookie", assertions: `{"session": {"secure": true, "httpOnly": true, "sameSite": "strict", "path": "/"}}`, wantSuccess: true, wantDetail: "[PASS] cookieAssertions: 1 cookie(s) as expected"},
+		{name: "attribute not required", assertions: `{"theme": {"httpOnly": false}}`, wantSuccess: true},
+		{name: "regression", path: "/regressed", assertions: `{"session": {"secure": true, "httpOnly": true, "sameSite": "Strict"}}`, wantDetail: `cookie session has Secure=false (expected true); cookie session has HttpOnly=false (expected true); cookie session has SameSite="Lax" (expected "Strict")`},
+		{name: "missing cookie", assertions: `{"csrf": {"secure": true}}`, wantDetail: "cookie csrf is not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": server.URL + tt.path, "method": "GET", "cookieAssertions": tt.assertions})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantDetail) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantDetail)
+			}
+		})
+	}
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "cookieAssertions": `{"session": {"secure": true}}`})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := CookieInfo{Name: "session", Secure: true, HttpOnly: true, SameSite: "Strict", Path: "/"}
+	if len(output.Cookies) != 1 || output.Cookies[0] != want {
+		t.Errorf("Cookies = %+v, want only %+v", output.Cookies, want)
+	}
+
+	for _, raw := range []string{`{}`, `{"session": {"secure": "yes"}}`, `{"session": {"sameSite": "Strict", "priority": "high"}}`, `not json`} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "cookieAssertions": raw}); err == nil {
+			t.Errorf("Expected error for cookieAssertions %s", raw)
+		}
+	}
+}
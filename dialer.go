@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ---- Dialer ----
+
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// defaultConnectTimeout and defaultTCPKeepAlive match the dialer of
+// http.DefaultTransport.
+const (
+	defaultConnectTimeout = 30 * time.Second
+	defaultTCPKeepAlive   = 30 * time.Second
+)
+
+// newDialContext returns the function the transport uses to open
+// connections, configured from config. The defaults match those of
+// http.DefaultTransport.
+func newDialContext(config map[string]string) (dialContextFunc, error) {
+	// 'connectTimeout' only bounds establishing the connection; the client
+	// 'timeout' still covers the whole request.
+	connectTimeout, err := configDuration(config, "connectTimeout", defaultConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if connectTimeout <= 0 {
+		return nil, fmt.Errorf("invalid 'connectTimeout' in config: must be positive")
+	}
+	// Keep-alive probes stop stateful load balancers from silently dropping
+	// pooled connections between polls. Go reads a zero KeepAlive as its
+	// default, so "0" is passed on as negative, which disables them.
+	keepAlive, err := configDuration(config, "tcpKeepAlive", defaultTCPKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	if keepAlive < 0 {
+		return nil, fmt.Errorf("invalid 'tcpKeepAlive' in config: must not be negative")
+	}
+	if keepAlive == 0 {
+		keepAlive = -1
+	}
+	dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: keepAlive}
+
+	localAddr := config["localAddr"]
+	if localAddr != "" {
+		addr, err := parseLocalAddr(localAddr)
+		if err != nil {
+			return nil, err
+		}
+		dialer.LocalAddr = addr
+	}
+
+	dnsServer := config["dnsServer"]
+	if dnsServer != "" {
+		if _, _, err := net.SplitHostPort(dnsServer); err != nil {
+			dnsServer = net.JoinHostPort(dnsServer, "53")
+		}
+		if _, err := net.ResolveUDPAddr("udp", dnsServer); err != nil {
+			return nil, fmt.Errorf("invalid 'dnsServer' %q: %w", config["dnsServer"], err)
+		}
+
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, dnsServer)
+			},
+		}
+	}
+
+	// 'network' pins the address family, e.g. to check that a canary is
+	// reachable over IPv6 on a dual-stack cluster.
+	family := config["network"]
+	switch family {
+	case "", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("invalid 'network' %q: expected tcp, tcp4 or tcp6", family)
+	}
+
+	tunnel, err := parseSSHTunnel(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if tunnel != nil {
+			return tunnel.dial(ctx, dialer, addr)
+		}
+		if family != "" {
+			network = family
+		}
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+
+		var dnsErr *net.DNSError
+		var netErr net.Error
+		var addrErr *net.AddrError
+		switch {
+		case family != "" && errors.As(err, &addrErr) && addrErr.Err == "no suitable address found":
+			return nil, fmt.Errorf("%s has no %s address, as required by 'network' %s: %w", addr, familyName[family], family, err)
+		case dnsServer != "" && errors.As(err, &dnsErr):
+			return nil, fmt.Errorf("failed to resolve %s via dnsServer %s: %w", dnsErr.Name, dnsServer, err)
+		case localAddr != "":
+			return nil, fmt.Errorf("failed to connect from localAddr %s: %w", localAddr, err)
+		case errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil:
+			return nil, fmt.Errorf("connection to %s not established within connectTimeout %s: %w", addr, connectTimeout, err)
+		}
+		return nil, err
+	}, nil
+}
+
+// familyName names the address family of each 'network' value.
+var familyName = map[string]string{"tcp": "IPv4 or IPv6", "tcp4": "IPv4", "tcp6": "IPv6"}
+
+// parseLocalAddr parses an "ip" or "ip:port" source address for outbound
+// connections.
+func parseLocalAddr(raw string) (*net.TCPAddr, error) {
+	host, port := raw, "0"
+	if h, p, err := net.SplitHostPort(raw); err == nil {
+		host, port = h, p
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid 'localAddr' %q: expected an IP address", raw)
+	}
+	addr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(ip.String(), port))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'localAddr' %q: %w", raw, err)
+	}
+	return addr, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestTCPKeepAlive(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	// keepAlive reads SO_KEEPALIVE and TCP_KEEPIDLE from a dialled connection
+	keepAlive := func(config map[string]string) (bool, int) {
+		t.Helper()
+		dial, err := newDialContext(config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		conn, err := dial(context.Background(), "tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		raw, err := conn.(*net.TCPConn).SyscallConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var enabled, idle int
+		raw.Control(func(fd uintptr) {
+			enabled, _ = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+			idle, _ = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		})
+		return enabled != 0, idle
+	}
+
+	if enabled, idle := keepAlive(map[string]string{}); !enabled || idle != 30 {
+		t.Errorf("Default keep-alive = %v after %ds idle, want enabled after 30s", enabled, idle)
+	}
+	if enabled, idle := keepAlive(map[string]string{"tcpKeepAlive": "10s"}); !enabled || idle != 10 {
+		t.Errorf("Keep-alive = %v after %ds idle, want enabled after 10s", enabled, idle)
+	}
+	if enabled, _ := keepAlive(map[string]string{"tcpKeepAlive": "0"}); enabled {
+		t.Error("Expected keep-alive to be disabled")
+	}
+
+	for _, raw := range []string{"-1s", "often"} {
+		if _, err := newDialContext(map[string]string{"tcpKeepAlive": raw}); err == nil {
+			t.Errorf("Expected error for tcpKeepAlive %q", raw)
+		}
+	}
+}
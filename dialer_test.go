@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDNSServer serves A records for the given names over UDP and returns
+// the server address. Other questions get an empty NOERROR answer.
+func startDNSServer(t *testing.T, records map[string]net.IP) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for DNS: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) == 0 {
+				continue
+			}
+
+			q := msg.Questions[0]
+			msg.Header.Response = true
+			msg.Header.Authoritative = true
+			if ip, ok := records[q.Name.String()]; ok && q.Type == dnsmessage.TypeA {
+				var a [4]byte
+				copy(a[:], ip.To4())
+				msg.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: a},
+				}}
+			}
+			reply, err := msg.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(reply, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestDNSServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	dns := startDNSServer(t, map[string]net.IP{"canary.internal.test.": net.ParseIP("127.0.0.1")})
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":       "http://canary.internal.test:" + u.Port(),
+		"method":    "GET",
+		"dnsServer": dns,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected successful response via custom DNS, got: %v", output.Message)
+	}
+
+	output, err = runPlugin(t, map[string]string{
+		"uri":       "http://unknown.internal.test:" + u.Port(),
+		"method":    "GET",
+		"dnsServer": dns,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "failed to resolve unknown.internal.test via dnsServer "+dns) {
+		t.Errorf("Expected resolution failure, got: %v", output.Message)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "dnsServer": "not a host:port:53"}); err == nil {
+		t.Error("Expected error for invalid dnsServer")
+	}
+}
+
+func TestLocalAddr(t *testing.T) {
+	var remote string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote = r.RemoteAddr
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "localAddr": "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !strings.HasPrefix(remote, "127.0.0.1:") {
+		t.Errorf("Expected request from 127.0.0.1, got %q: %v", remote, output.Message)
+	}
+
+	// 192.0.2.1 (TEST-NET-1) is never assigned to a local interface
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "localAddr": "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "failed to connect from localAddr 192.0.2.1") {
+		t.Errorf("Expected bind failure, got: %v", output.Message)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "localAddr": "eth0"}); err == nil {
+		t.Error("Expected error for non-IP localAddr")
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("slow body"))
+	}))
+	defer server.Close()
+
+	// A short connect timeout does not cut off a slow body
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "connectTimeout": "50ms"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !strings.Contains(output.Message, "slow body") {
+		t.Errorf("Expected the slow body to be read, got: %v", output.Message)
+	}
+
+	// An expired connect timeout is reported as such
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "connectTimeout": "1ns"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.FailureReason, "not established within connectTimeout 1ns") {
+		t.Errorf("Expected a connect timeout, got: %v", output.FailureReason)
+	}
+
+	for _, raw := range []string{"0s", "fast"} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "connectTimeout": raw}); err == nil {
+			t.Errorf("Expected error for connectTimeout %q", raw)
+		}
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "network": "tcp4"})
+	if err != nil || !output.Success {
+		t.Errorf("Expected an IPv4 request to succeed, got %v %+v", err, output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "network": "tcp6"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "has no IPv6 address, as required by 'network' tcp6") {
+		t.Errorf("Expected a missing IPv6 address to be reported, got %+v", output)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "network": "udp"}); err == nil {
+		t.Error("Expected error for unsupported network")
+	}
+
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	v6 := &httptest.Server{Listener: listener, Config: &http.Server{Handler: server.Config.Handler}}
+	v6.Start()
+	defer v6.Close()
+	output, err = runPlugin(t, map[string]string{"uri": v6.URL, "method": "GET", "network": "tcp6"})
+	if err != nil || !output.Success {
+		t.Errorf("Expected an IPv6 request to succeed, got %v %+v", err, output)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// ---- Fallback URI ----
+
+// Values of PluginOutput.Endpoint.
+const (
+	endpointPrimary  = "primary"
+	endpointFallback = "fallback"
+)
+
+// runWithFallback probes the primary 'uri', with its retries, and only
+// when that fails probes 'fallbackUri' with the same settings.
+func (p *HTTPPlugin) runWithFallback(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	if _, ok := config["requests"]; ok {
+		return PluginOutput{}, fmt.Errorf("'fallbackUri' cannot be combined with 'requests'")
+	}
+	mode, err := parseOutputMode(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	primary := make(map[string]string, len(config))
+	for key, value := range config {
+		if key != "fallbackUri" {
+			primary[key] = value
+		}
+	}
+	output, err := p.runEntry(ctx, primary)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if output.Success {
+		output.Endpoint = endpointPrimary
+		return output, nil
+	}
+
+	primaryReason := output.FailureReason
+	if primaryReason == "" {
+		primaryReason = "checks failed"
+	}
+	fallback := primary
+	fallback["uri"] = config["fallbackUri"]
+	if output, err = p.runEntry(ctx, fallback); err != nil {
+		return PluginOutput{}, fmt.Errorf("fallbackUri: %w", err)
+	}
+	output.Endpoint = endpointFallback
+	if mode != outputModeMinimal {
+		output.Message = fmt.Sprintf("Primary failed (%s), used fallback %s\n%s", primaryReason, fallback["uri"], output.Message)
+	}
+	if !output.Success && output.FailureReason != "" {
+		output.FailureReason = fmt.Sprintf("primary: %s; fallback: %s", primaryReason, output.FailureReason)
+	}
+	return output, nil
+}
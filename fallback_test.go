@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFallbackURI(t *testing.T) {
+	var primaryHits, fallbackHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/up":
+			primaryHits.Add(1)
+		case "/down":
+			primaryHits.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/fallback":
+			fallbackHits.Add(1)
+		case "/fallback-down":
+			fallbackHits.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		uri, fallback    string
+		wantSuccess      bool
+		wantEndpoint     string
+		wantPrimaryHits  int32
+		wantFallbackHits int32
+		wantMessage      string
+	}{
+		{name: "primary passes", uri: "/up", fallback: "/fallback", wantSuccess: true, wantEndpoint: "primary", wantPrimaryHits: 1},
+		{name: "fallback after retries", uri: "/down", fallback: "/fallback", wantSuccess: true, wantEndpoint: "fallback", wantPrimaryHits: 3, wantFallbackHits: 1, wantMessage: "Primary failed (checks failed), used fallback " + server.URL + "/fallback"},
+		{name: "both fail", uri: "/down", fallback: "/fallback-down", wantEndpoint: "fallback", wantPrimaryHits: 3, wantFallbackHits: 3},
+		{name: "unreachable primary", uri: "http://127.0.0.1:1/", fallback: "/fallback", wantSuccess: true, wantEndpoint: "fallback", wantFallbackHits: 1, wantMessage: "connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryHits.Store(0)
+			fallbackHits.Store(0)
+			uri := tt.uri
+			if strings.HasPrefix(uri, "/") {
+				uri = server.URL + uri
+			}
+			output, err := runPlugin(t, map[string]string{
+				"uri":          uri,
+				"fallbackUri":  server.URL + tt.fallback,
+				"method":       "GET",
+				"retries":      "2",
+				"retryBackoff": "1ms",
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess || output.Endpoint != tt.wantEndpoint {
+				t.Errorf("got success=%v endpoint=%q, want %v/%q (message: %s)", output.Success, output.Endpoint, tt.wantSuccess, tt.wantEndpoint, output.Message)
+			}
+			if primaryHits.Load() != tt.wantPrimaryHits || fallbackHits.Load() != tt.wantFallbackHits {
+				t.Errorf("hits = %d primary, %d fallback, want %d, %d", primaryHits.Load(), fallbackHits.Load(), tt.wantPrimaryHits, tt.wantFallbackHits)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	if _, err := runPlugin(t, map[string]string{"fallbackUri": server.URL, "requests": `[{"uri": "` + server.URL + `"}]`, "method": "GET"}); err == nil {
+		t.Error("Expected error when fallbackUri is combined with requests")
+	}
+}
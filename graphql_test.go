@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Accept") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !strings.Contains(req.Query, "health") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if req.Variables["region"] == "eu" {
+			w.Write([]byte(`{"data": {"health": {"status": "OK", "version": "2.1.0"}}}`))
+			return
+		}
+		w.Write([]byte(`{"data": null, "errors": [{"message": "unknown region"}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		config      map[string]string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{
+			name:        "data assertion",
+			config:      map[string]string{"graphqlVariables": `{"region": "eu"}`, "jsonPath": "data.health.status", "expectedValue": "OK"},
+			wantSuccess: true,
+		},
+		{
+			name:        "regex assertion",
+			config:      map[string]string{"graphqlVariables": `{"region": "eu"}`, "jsonPath": "data.health.version", "expectedValue": "regex:^2\\."},
+			wantSuccess: true,
+		},
+		{
+			name:        "errors surfaced",
+			config:      map[string]string{"graphqlVariables": `{"region": "us"}`, "jsonPath": "data.health.status", "expectedValue": "OK"},
+			wantSuccess: false,
+			wantMessage: `[FAIL] jsonPath data.health.status: cannot read field "health" of null`,
+		},
+		{
+			name:        "assert on errors",
+			config:      map[string]string{"graphqlVariables": `{"region": "us"}`, "jsonPath": "errors[0].message", "expectedValue": "unknown region"},
+			wantSuccess: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL, "graphqlQuery": "query($region: String) { health(region: $region) { status version } }"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "graphqlQuery": "{ health }", "graphqlVariables": "{region"}); err == nil {
+		t.Error("Expected error for invalid graphqlVariables")
+	}
+}
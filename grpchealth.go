@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ---- gRPC Health Checks ----
+
+// runGRPCHealth probes 'uri' (host:port) with the standard grpc.health.v1
+// Check RPC. The step succeeds when 'grpcService' reports SERVING.
+func (p *HTTPPlugin) runGRPCHealth(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	target := strings.TrimPrefix(configOrEnv(config, "uri", "PLUGIN_DEFAULT_URI"), "grpc://")
+	if target == "" || strings.Contains(target, "/") {
+		return PluginOutput{}, fmt.Errorf("'grpcHealth' requires 'uri' as host:port, got %q", target)
+	}
+
+	mode, err := parseOutputMode(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	timeout, err := configDuration(config, "timeout", defaultTimeout)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	useTLS, err := configBool(config, "grpcTls", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		tlsConfig, err := buildTLSConfig(config)
+		if err != nil {
+			return PluginOutput{}, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return PluginOutput{}, fmt.Errorf("invalid gRPC target %q: %w", target, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	service := config["grpcService"]
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return errorOutput(mode, fmt.Errorf("gRPC health check failed: %w", err)), nil
+	}
+
+	status := resp.GetStatus()
+	result := PluginOutput{Success: status == healthpb.HealthCheckResponse_SERVING}
+	if mode != outputModeMinimal {
+		if service == "" {
+			service = "(server)"
+		}
+		result.Message = fmt.Sprintf("Health: %s\nService: %s", status, service)
+		if mode == outputModeSummary {
+			result.Message += fmt.Sprintf("\nDuration: %s", time.Since(start).Round(time.Millisecond))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCHealth(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("checkout", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("payments", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	tests := []struct {
+		name        string
+		config      map[string]string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{name: "server health", config: map[string]string{}, wantSuccess: true, wantMessage: "Health: SERVING\nService: (server)"},
+		{name: "serving service", config: map[string]string{"grpcService": "checkout"}, wantSuccess: true, wantMessage: "Service: checkout"},
+		{name: "not serving", config: map[string]string{"grpcService": "payments"}, wantMessage: "Health: NOT_SERVING"},
+		{name: "unknown service", config: map[string]string{"grpcService": "search"}, wantMessage: "code = NotFound"},
+		{name: "grpc scheme", config: map[string]string{"uri": "grpc://" + ln.Addr().String()}, wantSuccess: true},
+		{name: "footer", config: map[string]string{"outputFooter": "true"}, wantSuccess: true, wantMessage: "\nCURL_RESULT status=0 "},
+		{name: "TLS against plaintext", config: map[string]string{"grpcTls": "true", "timeout": "500ms"}, wantMessage: "gRPC health check failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": ln.Addr().String(), "grpcHealth": "true"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	// The check is reported to the pushgateway like an HTTP probe
+	pushes := make(chan string, 1)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushes <- string(body)
+	}))
+	defer gateway.Close()
+	output, err := runPlugin(t, map[string]string{"uri": ln.Addr().String(), "grpcHealth": "true", "pushgatewayUrl": gateway.URL})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected success, got %v %+v", err, output)
+	}
+	select {
+	case body := <-pushes:
+		if !strings.Contains(body, "rollout_probe_success 1\n") {
+			t.Errorf("Unexpected push %q", body)
+		}
+	default:
+		t.Error("Expected the check to be pushed")
+	}
+
+	for _, uri := range []string{"", "http://127.0.0.1:50051/health"} {
+		if _, err := runPlugin(t, map[string]string{"uri": uri, "grpcHealth": "true"}); err == nil {
+			t.Errorf("Expected error for uri %q", uri)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeadRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", "1234")
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "HEAD"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.ContentLength != 1234 {
+		t.Errorf("Success = %v, ContentLength = %d; want true, 1234", output.Success, output.ContentLength)
+	}
+	if output.Message != "Status: 200 OK\nContent-Length: 1234" {
+		t.Errorf("Unexpected message %q", output.Message)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/missing", "method": "HEAD"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success {
+		t.Error("Expected HEAD of missing resource to fail")
+	}
+}
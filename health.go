@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+)
+
+// ---- Health Endpoint ----
+
+// healthAddrEnv names the environment variable that enables the health
+// listener, e.g. PLUGIN_HEALTH_ADDR=":8081". It is disabled when unset.
+const healthAddrEnv = "PLUGIN_HEALTH_ADDR"
+
+func newHealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// startHealthServer listens on addr and serves /healthz in the background.
+// It returns once the listener is bound so bind errors surface to the caller.
+func startHealthServer(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := http.Serve(ln, newHealthHandler()); err != nil {
+			log.Printf("Health server stopped: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	server := httptest.NewServer(newHealthHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("Failed to query health endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, err = http.Get(server.URL + "/other")
+	if err != nil {
+		t.Fatalf("Failed to query unknown path: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /other = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
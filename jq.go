@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// ---- jq ----
+
+// jqFilter is a compiled 'jq' expression.
+type jqFilter struct {
+	expr string
+	code *gojq.Code
+}
+
+func compileJQ(expr string) (*jqFilter, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'jq' expression: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'jq' expression: %w", err)
+	}
+	return &jqFilter{expr: expr, code: code}, nil
+}
+
+// criterion applies the filter to the JSON body and matches the result
+// against expected, like 'jsonPath'. An expression that produces several
+// values yields them one per line; one that produces none fails.
+func (f *jqFilter) criterion(ctx context.Context, expected *valueMatcher, body []byte) criterion {
+	cr := criterion{name: "jq " + f.expr}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		cr.detail = fmt.Sprintf("response is not valid JSON: %v", err)
+		return cr
+	}
+
+	var results []string
+	iter := f.code.RunWithContext(ctx, doc)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			cr.detail = fmt.Sprintf("jq error: %v", err)
+			return cr
+		}
+		results = append(results, jsonValueString(v))
+	}
+	if len(results) == 0 {
+		cr.detail = "jq produced no result"
+		return cr
+	}
+
+	actual := strings.Join(results, "\n")
+	if expected == nil {
+		cr.passed, cr.detail = true, actual
+		return cr
+	}
+	cr.passed = expected.match(actual)
+	if cr.passed {
+		cr.detail = actual
+	} else {
+		cr.detail = fmt.Sprintf("got %q (expected %q)", actual, expected.raw)
+	}
+	return cr
+}
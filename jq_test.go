@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJQ(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			w.Write([]byte("not json"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"pods": [
+			{"name": "a", "ready": true, "restarts": 0},
+			{"name": "b", "ready": false, "restarts": 3},
+			{"name": "c", "ready": true, "restarts": 1}
+		]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		jq          string
+		expected    string
+		wantSuccess bool
+		wantDetail  string
+	}{
+		{name: "filter and count", jq: "[.pods[] | select(.ready)] | length", expected: "2", wantSuccess: true, wantDetail: "[PASS] jq [.pods[] | select(.ready)] | length: 2"},
+		{name: "aggregate", jq: "[.pods[].restarts] | add", expected: "regex:^[0-3]$", wantDetail: `got "4"`},
+		{name: "several results", jq: ".pods[] | select(.ready) | .name", expected: "a\nc", wantSuccess: true},
+		{name: "no expected value", jq: ".pods[0].name", wantSuccess: true, wantDetail: "[PASS] jq .pods[0].name: a"},
+		{name: "no result", jq: ".pods[] | select(.restarts > 5)", wantDetail: "jq produced no result"},
+		{name: "runtime error", jq: ".pods[0].name + 1", wantDetail: "jq error:"},
+		{name: "not json", path: "/text", jq: ".", wantDetail: "response is not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET", "jq": tt.jq}
+			if tt.expected != "" {
+				config["expectedValue"] = tt.expected
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantDetail) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantDetail)
+			}
+		})
+	}
+
+	for _, config := range []map[string]string{
+		{"jq": ".pods[] |"},
+		{"jq": "undefined_function(1)"},
+		{"jq": ".", "method": "HEAD"},
+	} {
+		config["uri"] = server.URL
+		if config["method"] == "" {
+			config["method"] = "GET"
+		}
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ---- JSON Path ----
+
+// evalJSONPath resolves a simple path such as "data.items[0].name" (an
+// optional leading "$." is ignored) against a decoded JSON document.
+func evalJSONPath(doc interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	current := doc
+	if path == "" {
+		return current, nil
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(segment, "[")
+		if name != "" {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot read field %q of %s", name, jsonKind(current))
+			}
+			if current, ok = obj[name]; !ok {
+				return nil, fmt.Errorf("field %q not found", name)
+			}
+		}
+
+		for rest != "" {
+			index, remainder, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("unterminated index in %q", segment)
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in %q", index, segment)
+			}
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s", jsonKind(current))
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range (length %d)", i, len(arr))
+			}
+			current = arr[i]
+			rest = strings.TrimPrefix(remainder, "[")
+		}
+	}
+	return current, nil
+}
+
+// jsonValueString renders a decoded JSON value for comparison: strings
+// as-is, everything else in its JSON form.
+func jsonValueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
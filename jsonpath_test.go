@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"data": {"items": [{"name": "a"}, {"name": "b", "tags": [["x", "y"]]}], "count": 2, "ready": true},
+		"errors": null
+	}`), &doc); err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "data.count", want: "2"},
+		{path: "$.data.ready", want: "true"},
+		{path: "data.items[1].name", want: "b"},
+		{path: "data.items[1].tags[0][1]", want: "y"},
+		{path: "errors", want: "null"},
+		{path: "data.items[0]", want: `{"name":"a"}`},
+		{path: "data.missing", wantErr: true},
+		{path: "data.items[5]", wantErr: true},
+		{path: "data.count.value", wantErr: true},
+		{path: "data.items[x]", wantErr: true},
+		{path: "data.items[0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		value, err := evalJSONPath(doc, tt.path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("evalJSONPath(%q) = %v, want error", tt.path, value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("evalJSONPath(%q) unexpected error: %v", tt.path, err)
+			continue
+		}
+		if got := jsonValueString(value); got != tt.want {
+			t.Errorf("evalJSONPath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
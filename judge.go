@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ---- External Judge ----
+
+const defaultJudgeTimeout = 5 * time.Second
+
+// maxJudgeBodyBytes bounds the response body forwarded to the judge.
+const maxJudgeBodyBytes = 1 << 20
+
+// judge delegates the pass/fail decision to the webhook at 'judgeUrl'.
+type judge struct {
+	url     string
+	timeout time.Duration
+}
+
+// judgeRequest is the JSON document posted to the judge.
+type judgeRequest struct {
+	URL        string              `json:"url"`
+	Status     int                 `json:"status"`
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body"`
+	DurationMs float64             `json:"durationMs"`
+	// BodyTruncated is set when Body holds only the first
+	// maxJudgeBodyBytes of the response body.
+	BodyTruncated bool `json:"bodyTruncated,omitempty"`
+}
+
+// parseJudge reads 'judgeUrl' and 'judgeTimeout'. It returns nil when no
+// judge is configured.
+func parseJudge(config map[string]string) (*judge, error) {
+	raw := config["judgeUrl"]
+	if raw == "" {
+		if _, ok := config["judgeTimeout"]; ok {
+			return nil, fmt.Errorf("'judgeTimeout' requires 'judgeUrl'")
+		}
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(raw); err != nil {
+		return nil, fmt.Errorf("invalid 'judgeUrl' in config: %w", err)
+	}
+	if key := firstKey(config, []string{"celExpression", "expectFailure"}); key != "" {
+		return nil, fmt.Errorf("'judgeUrl' cannot be combined with '%s'", key)
+	}
+	timeout, err := configDuration(config, "judgeTimeout", defaultJudgeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid 'judgeTimeout' in config: must be positive")
+	}
+	return &judge{url: raw, timeout: timeout}, nil
+}
+
+// criterion posts the response to the judge, which passes it by answering
+// with a 2xx status. The judge's own timeout applies, not the request's.
+// The proxy, TLS and tunnel settings describe how to reach the probed
+// target, so the judge is called with the default client instead.
+func (j *judge) criterion(ctx context.Context, resp *http.Response, body []byte, duration time.Duration) criterion {
+	cr := criterion{name: "judge"}
+
+	truncated := len(body) > maxJudgeBodyBytes
+	if truncated {
+		body = body[:maxJudgeBodyBytes]
+	}
+	payload, err := json.Marshal(judgeRequest{
+		URL:           resp.Request.URL.Redacted(),
+		Status:        resp.StatusCode,
+		Headers:       resp.Header,
+		Body:          string(body),
+		DurationMs:    milliseconds(duration),
+		BodyTruncated: truncated,
+	})
+	if err != nil {
+		cr.detail = fmt.Sprintf("failed to encode the response: %v", err)
+		return cr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.url, bytes.NewReader(payload))
+	if err != nil {
+		cr.detail = err.Error()
+		return cr
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	judged, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cr.detail = fmt.Sprintf("judge call failed: %v", err)
+		return cr
+	}
+	defer judged.Body.Close()
+	// The judge may explain its verdict in a short body
+	reason, _ := io.ReadAll(io.LimitReader(judged.Body, 256))
+
+	cr.passed = judged.StatusCode/100 == 2
+	cr.detail = judged.Status
+	if r := strings.TrimSpace(string(reason)); r != "" {
+		cr.detail += ": " + r
+	}
+	return cr
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJudge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Build", "42")
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/stalled":
+			w.Write([]byte(`{"orders": 0}`))
+			return
+		case "/large":
+			w.Write([]byte(`{"orders": 17, "padding": "` + strings.Repeat("x", 2*maxJudgeBodyBytes) + `"}`))
+			return
+		}
+		w.Write([]byte(`{"orders": 17}`))
+	}))
+	defer server.Close()
+
+	var received judgeRequest
+	judgeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The judge accepts a 500 as long as orders are still flowing
+		if !strings.Contains(received.Body, `"orders": 17`) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte("orders stalled"))
+		}
+	}))
+	defer judgeServer.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL + "/error", "method": "GET", "judgeUrl": judgeServer.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !strings.Contains(output.Message, "[PASS] judge: 200 OK") {
+		t.Errorf("Expected the judge to pass the response, got %+v", output)
+	}
+	if received.Status != 500 || received.URL != server.URL+"/error" || received.Headers["X-Build"][0] != "42" || received.DurationMs <= 0 {
+		t.Errorf("Judge received %+v", received)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "judgeUrl": judgeServer.URL, "bodyMatch": "17"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected success, got %+v", output)
+	}
+
+	// Only the start of a large body is forwarded
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/large", "method": "GET", "judgeUrl": judgeServer.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || len(received.Body) != maxJudgeBodyBytes || !received.BodyTruncated {
+		t.Errorf("Expected a truncated body to pass, got %d bytes (truncated %v), success %v", len(received.Body), received.BodyTruncated, output.Success)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/stalled", "method": "GET", "judgeUrl": judgeServer.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "[FAIL] judge: 422 Unprocessable Entity: orders stalled") {
+		t.Errorf("Expected the judge to fail the response, got %+v", output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "judgeUrl": judgeServer.URL + "/slow", "judgeTimeout": "50ms"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "judge call failed") {
+		t.Errorf("Expected the judge timeout to fail the step, got %+v", output)
+	}
+
+	for _, config := range []map[string]string{
+		{"judgeUrl": "not a url"},
+		{"judgeTimeout": "1s"},
+		{"judgeUrl": judgeServer.URL, "judgeTimeout": "0s"},
+		{"judgeUrl": judgeServer.URL, "celExpression": "true"},
+		{"judgeUrl": judgeServer.URL, "expectFailure": "404"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
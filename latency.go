@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ---- Latency Sampling ----
+
+// LatencyReport summarises the timed samples taken for a latency gate.
+// Durations are in milliseconds.
+type LatencyReport struct {
+	Percentile  float64   `json:"percentile"`
+	ValueMs     float64   `json:"valueMs"`
+	ThresholdMs float64   `json:"thresholdMs,omitempty"`
+	SamplesMs   []float64 `json:"samplesMs"`
+	// Errors counts samples that got no response; they are not timed.
+	Errors int `json:"errors,omitempty"`
+}
+
+// latencyGate holds the 'samples', 'latencyPercentile' and
+// 'latencyThreshold' settings. A zero samples count disables sampling.
+type latencyGate struct {
+	samples    int
+	percentile float64
+	threshold  time.Duration
+}
+
+func parseLatencyGate(config map[string]string) (latencyGate, error) {
+	samples, err := configInt(config, "samples", 0)
+	if err != nil {
+		return latencyGate{}, err
+	}
+	if samples < 0 {
+		return latencyGate{}, fmt.Errorf("invalid 'samples' in config: must not be negative")
+	}
+	percentile, err := configFloat(config, "latencyPercentile", 95)
+	if err != nil {
+		return latencyGate{}, err
+	}
+	if percentile <= 0 || percentile > 100 {
+		return latencyGate{}, fmt.Errorf("invalid 'latencyPercentile' in config: must be in (0, 100]")
+	}
+	threshold, err := configDuration(config, "latencyThreshold", 0)
+	if err != nil {
+		return latencyGate{}, err
+	}
+	if threshold > 0 && samples == 0 {
+		return latencyGate{}, fmt.Errorf("'latencyThreshold' requires 'samples'")
+	}
+	return latencyGate{samples: samples, percentile: percentile, threshold: threshold}, nil
+}
+
+// sample sends g.samples copies of req one after another and times each
+// until its body has been read.
+func (g latencyGate) sample(ctx context.Context, client *http.Client, req *http.Request) (*LatencyReport, error) {
+	report := &LatencyReport{Percentile: g.percentile, ThresholdMs: milliseconds(g.threshold)}
+	var durations []time.Duration
+	for i := 0; i < g.samples; i++ {
+		next, err := cloneRequest(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := client.Do(next)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			report.Errors++
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		d := time.Since(start)
+		durations = append(durations, d)
+		report.SamplesMs = append(report.SamplesMs, milliseconds(d))
+	}
+	report.ValueMs = milliseconds(percentile(durations, g.percentile))
+	return report, nil
+}
+
+// criterion gates on the computed percentile. Without a threshold it only
+// reports the value; failed samples always fail the gate.
+func (g latencyGate) criterion(report *LatencyReport) criterion {
+	cr := criterion{name: fmt.Sprintf("latency p%g", g.percentile), passed: true}
+	cr.detail = fmt.Sprintf("%gms over %d samples", report.ValueMs, len(report.SamplesMs))
+	if g.threshold > 0 {
+		cr.passed = report.ValueMs <= report.ThresholdMs
+		cr.detail += fmt.Sprintf(" (threshold %s)", g.threshold)
+	}
+	if report.Errors > 0 {
+		cr.passed = false
+		cr.detail += fmt.Sprintf("; %d of %d samples failed", report.Errors, g.samples)
+	}
+	return cr
+}
+
+// percentile returns the p-th percentile of durations using the
+// nearest-rank method, or 0 when there are none.
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		var out []time.Duration
+		for _, v := range values {
+			out = append(out, time.Duration(v)*time.Millisecond)
+		}
+		return out
+	}
+
+	tests := []struct {
+		samples []time.Duration
+		p       float64
+		want    time.Duration
+	}{
+		{samples: nil, p: 95, want: 0},
+		{samples: ms(7), p: 50, want: 7 * time.Millisecond},
+		{samples: ms(5, 1, 4, 2, 3), p: 50, want: 3 * time.Millisecond},
+		{samples: ms(5, 1, 4, 2, 3), p: 95, want: 5 * time.Millisecond},
+		{samples: ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 100), p: 95, want: 19 * time.Millisecond},
+		{samples: ms(1, 2, 3, 4), p: 100, want: 4 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.samples, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %g) = %s, want %s", tt.samples, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyGate(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		config      map[string]string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{
+			name:        "under threshold",
+			path:        "/fast",
+			config:      map[string]string{"samples": "4", "latencyThreshold": "40ms"},
+			wantSuccess: true,
+			wantMessage: "[PASS] latency p95: ",
+		},
+		{
+			name:        "over threshold",
+			path:        "/slow",
+			config:      map[string]string{"samples": "4", "latencyThreshold": "40ms"},
+			wantSuccess: false,
+			wantMessage: "[FAIL] latency p95: ",
+		},
+		{
+			name:        "report only",
+			path:        "/slow",
+			config:      map[string]string{"samples": "4", "latencyPercentile": "50"},
+			wantSuccess: true,
+			wantMessage: "[PASS] latency p50: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+			if output.Latency == nil || len(output.Latency.SamplesMs) != 4 {
+				t.Fatalf("expected 4 latency samples, got %+v", output.Latency)
+			}
+			if got := hits.Load(); got != 5 {
+				t.Errorf("expected the probe plus 4 samples, got %d requests", got)
+			}
+		})
+	}
+}
+
+func TestLatencyGateInvalid(t *testing.T) {
+	for _, config := range []map[string]string{
+		{"samples": "-1"},
+		{"samples": "3", "latencyPercentile": "0"},
+		{"samples": "3", "latencyPercentile": "101"},
+		{"samples": "3", "latencyPercentile": "p95"},
+		{"latencyThreshold": "100ms"},
+	} {
+		config["uri"] = "http://127.0.0.1"
+		config["method"] = "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
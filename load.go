@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ---- Load Probes ----
+
+// maxLoadRequests bounds 'load' so that a typo cannot turn a canary check
+// into a real load test.
+const maxLoadRequests = 10000
+
+// LoadResult summarises the requests sent for 'load'. Durations are in
+// milliseconds and cover the requests that got a response.
+type LoadResult struct {
+	Requests    int `json:"requests"`
+	Concurrency int `json:"concurrency"`
+	// StatusCounts counts responses by status code; Errors counts requests
+	// that got no response at all.
+	StatusCounts map[int]int `json:"statusCounts"`
+	Errors       int         `json:"errors,omitempty"`
+	// Failed counts errors and responses failing the status check.
+	Failed       int     `json:"failed"`
+	ErrorRate    float64 `json:"errorRate"`
+	MaxErrorRate float64 `json:"maxErrorRate"`
+	MinMs        float64 `json:"minMs"`
+	AvgMs        float64 `json:"avgMs"`
+	P50Ms        float64 `json:"p50Ms"`
+	P95Ms        float64 `json:"p95Ms"`
+	P99Ms        float64 `json:"p99Ms"`
+}
+
+// loadTest is the 'load' setting: totalRequests copies of the request,
+// concurrency at a time. The step passes while the share of failed
+// requests stays within maxErrorRate.
+type loadTest struct {
+	Concurrency   int     `json:"concurrency"`
+	TotalRequests int     `json:"totalRequests"`
+	MaxErrorRate  float64 `json:"maxErrorRate"`
+}
+
+func parseLoadTest(config map[string]string) (*loadTest, error) {
+	raw, ok := config["load"]
+	if !ok {
+		return nil, nil
+	}
+	l := &loadTest{Concurrency: 1}
+	if err := json.Unmarshal([]byte(raw), l); err != nil {
+		return nil, fmt.Errorf("invalid 'load' in config: %w", err)
+	}
+	if l.TotalRequests < 1 || l.TotalRequests > maxLoadRequests {
+		return nil, fmt.Errorf("invalid 'load' in config: totalRequests must be between 1 and %d", maxLoadRequests)
+	}
+	if l.Concurrency < 1 {
+		return nil, fmt.Errorf("invalid 'load' in config: concurrency must be positive")
+	}
+	if l.MaxErrorRate < 0 || l.MaxErrorRate > 1 {
+		return nil, fmt.Errorf("invalid 'load' in config: maxErrorRate must be between 0 and 1")
+	}
+	l.Concurrency = min(l.Concurrency, l.TotalRequests)
+	return l, nil
+}
+
+// run sends the requests from l.Concurrency workers. Each response is read
+// to the end before it is timed and counted.
+func (l *loadTest) run(ctx context.Context, client *http.Client, req *http.Request, acceptNotModified bool) (*LoadResult, error) {
+	result := &LoadResult{
+		Requests:     l.TotalRequests,
+		Concurrency:  l.Concurrency,
+		StatusCounts: make(map[int]int),
+		MaxErrorRate: l.MaxErrorRate,
+	}
+	var (
+		mu        sync.Mutex
+		durations []time.Duration
+		firstErr  error
+	)
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < l.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				next, err := cloneRequest(ctx, req)
+				if err != nil {
+					mu.Lock()
+					firstErr = err
+					mu.Unlock()
+					continue
+				}
+				start := time.Now()
+				resp, err := client.Do(next)
+				var d time.Duration
+				if err == nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+					d = time.Since(start)
+				}
+
+				mu.Lock()
+				if err != nil {
+					result.Errors++
+					result.Failed++
+				} else {
+					result.StatusCounts[resp.StatusCode]++
+					if !statusCriterion(resp, acceptNotModified).passed {
+						result.Failed++
+					}
+					durations = append(durations, d)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < l.TotalRequests && ctx.Err() == nil; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	result.ErrorRate = float64(result.Failed) / float64(result.Requests)
+	if len(durations) > 0 {
+		var total time.Duration
+		minimum := durations[0]
+		for _, d := range durations {
+			total += d
+			minimum = min(minimum, d)
+		}
+		result.MinMs = milliseconds(minimum)
+		result.AvgMs = milliseconds(total / time.Duration(len(durations)))
+		result.P50Ms = milliseconds(percentile(durations, 50))
+		result.P95Ms = milliseconds(percentile(durations, 95))
+		result.P99Ms = milliseconds(percentile(durations, 99))
+	}
+	return result, nil
+}
+
+// criterion gates on the error rate of the load run.
+func (l *loadTest) criterion(result *LoadResult) criterion {
+	return criterion{
+		name:   "load",
+		passed: result.ErrorRate <= result.MaxErrorRate,
+		detail: fmt.Sprintf("%d of %d requests failed (error rate %g, max %g); p50 %gms, p95 %gms, p99 %gms",
+			result.Failed, result.Requests, result.ErrorRate, result.MaxErrorRate, result.P50Ms, result.P95Ms, result.P99Ms),
+	}
+}
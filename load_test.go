@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	var hits, inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if current <= p || peak.CompareAndSwap(p, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		// Every fifth request fails on /flaky
+		if r.URL.Path == "/flaky" && n%5 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "load": `{"concurrency": 4, "totalRequests": 20}`})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.Load == nil {
+		t.Fatalf("Expected success with a load result, got %+v", output)
+	}
+	if got := hits.Load(); got != 21 {
+		t.Errorf("Server saw %d requests, want 21 (the probe and 20 for the load)", got)
+	}
+	if p := peak.Load(); p < 2 || p > 4 {
+		t.Errorf("Peak concurrency = %d, want between 2 and 4", p)
+	}
+	load := output.Load
+	if load.StatusCounts[200] != 20 || load.Failed != 0 || load.Concurrency != 4 {
+		t.Errorf("Unexpected load result: %+v", load)
+	}
+	if load.MinMs < 5 || load.P50Ms < load.MinMs || load.P99Ms < load.P95Ms || load.AvgMs < load.MinMs {
+		t.Errorf("Inconsistent latency stats: %+v", load)
+	}
+
+	hits.Store(0)
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/flaky", "method": "GET", "load": `{"concurrency": 2, "totalRequests": 19, "maxErrorRate": 0.1}`})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || output.Load.StatusCounts[503] != 4 || output.Load.Failed != 4 {
+		t.Errorf("Expected 4 of 19 requests to fail the gate, got %+v", output.Load)
+	}
+	if !strings.Contains(output.Message, "[FAIL] load: 4 of 19 requests failed") {
+		t.Errorf("Message = %q, want the load criterion", output.Message)
+	}
+
+	hits.Store(0)
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/flaky", "method": "GET", "load": `{"concurrency": 2, "totalRequests": 19, "maxErrorRate": 0.25}`})
+	if err != nil || !output.Success {
+		t.Errorf("Expected the error rate to be within 0.25, got %v %+v", err, output)
+	}
+
+	for _, raw := range []string{
+		`{"concurrency": 2}`,
+		`{"totalRequests": 100000}`,
+		`{"totalRequests": 5, "concurrency": 0}`,
+		`{"totalRequests": 5, "maxErrorRate": 2}`,
+		`not json`,
+	} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "load": raw}); err == nil {
+			t.Errorf("Expected error for load %s", raw)
+		}
+	}
+}
@@ -2,18 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"sync/atomic"
 	"time"
 
 	"net/rpc"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 // ---- Handshake config ----
@@ -31,6 +42,87 @@ type PluginInput struct {
 type PluginOutput struct {
 	Message string `json:"message"`
 	Success bool   `json:"success"`
+	// Value is the metric value with 'outputFormat' argoMetric, also given
+	// as the Message.
+	Value *float64 `json:"value,omitempty"`
+	// Phase is how the outcome should be interpreted, from 'onSuccess' and
+	// 'onFailure': Successful, Failed, Inconclusive or Error.
+	Phase string `json:"phase,omitempty"`
+	// FailureReason explains why the probe failed when it did not get a
+	// usable response.
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// Protocol is the protocol the response was received with, e.g. HTTP/2.0.
+	Protocol string `json:"protocol,omitempty"`
+	// TLS describes the server certificate on https connections.
+	TLS *TLSInfo `json:"tls,omitempty"`
+	// Cookies describes the cookies named in 'cookieAssertions' that the
+	// response sets.
+	Cookies []CookieInfo `json:"cookies,omitempty"`
+	// ContentType is the response Content-Type header.
+	ContentType string `json:"contentType,omitempty"`
+	// Compressed reports whether the response was compressed on the wire,
+	// whether or not it was transparently decompressed.
+	Compressed bool `json:"compressed,omitempty"`
+	// ContentLength is the response Content-Length, when known.
+	ContentLength int64 `json:"contentLength,omitempty"`
+	// BodySha256 is the hex-encoded SHA-256 of the response body.
+	BodySha256 string `json:"bodySha256,omitempty"`
+	// CorrelationID is the ID sent in the correlation ID header, so the probe
+	// can be found in server-side logs.
+	CorrelationID string `json:"correlationId,omitempty"`
+	// Streak is the number of consecutive passing attempts reached, and
+	// Attempts the number made, with 'requiredConsecutiveSuccesses'.
+	Streak   int `json:"streak,omitempty"`
+	Attempts int `json:"attempts,omitempty"`
+	// RedirectCount is the number of redirects followed to the response.
+	RedirectCount int `json:"redirectCount,omitempty"`
+	// Target is the uri picked from 'weightedUris'.
+	Target string `json:"target,omitempty"`
+	// Endpoint reports whether the primary 'uri' or the 'fallbackUri'
+	// produced the result, when a fallback is configured.
+	Endpoint string `json:"endpoint,omitempty"`
+	// FirstEvent is the data of the first event read with 'sse'.
+	FirstEvent string `json:"firstEvent,omitempty"`
+	// RequestEcho describes the request that was sent, with 'echoRequest'.
+	RequestEcho *RequestEcho `json:"request,omitempty"`
+	// Latency holds the latency samples, when 'samples' is set.
+	Latency *LatencyReport `json:"latency,omitempty"`
+	// Pages is the number of pages read with 'followPagination', and
+	// TotalItems the number of items found across them.
+	Pages      int `json:"pages,omitempty"`
+	TotalItems int `json:"totalItems,omitempty"`
+	// RequestAttempts is the number of attempts made with 'retryDuration'
+	// or 'retryOnBodyMismatch', and ElapsedMs the time they took.
+	RequestAttempts int     `json:"requestAttempts,omitempty"`
+	ElapsedMs       float64 `json:"elapsedMs,omitempty"`
+	// Sequence holds the values extracted by 'sequenceAssertion', one per
+	// batch request.
+	Sequence []float64 `json:"sequence,omitempty"`
+	// Load holds the aggregate statistics of the requests sent for 'load'.
+	Load *LoadResult `json:"load,omitempty"`
+
+	// ServerTimings holds the metrics of the response's Server-Timing
+	// header, which separate server processing time from network latency.
+	ServerTimings []ServerTiming `json:"serverTimings,omitempty"`
+	// Trailers holds the response trailers, with 'includeTrailers'.
+	Trailers map[string]string `json:"trailers,omitempty"`
+	// Diff describes the first difference found with 'compareUri'.
+	Diff string `json:"diff,omitempty"`
+
+	// ConnectionReuse reports, per batch request, whether it reused a
+	// connection, with 'requireConnectionReuse'.
+	ConnectionReuse []bool `json:"connectionReuse,omitempty"`
+
+	// body is the response body, for checks across batch requests, and
+	// status and header the rest of the response, for 'compareUri'.
+	// reused is whether the request got a pooled connection, and duration
+	// how long it took.
+	body     []byte
+	status   int
+	header   http.Header
+	reused   bool
+	duration time.Duration
 }
 
 // ---- StepPlugin Interface ----
@@ -39,42 +131,785 @@ type StepPlugin interface {
 }
 
 // ---- Plugin Implementation ----
-type HTTPPlugin struct{}
+
+// defaultTimeout bounds a single request attempt when 'timeout' is not set.
+const defaultTimeout = 10 * time.Second
+
+type HTTPPlugin struct {
+	// Logger receives non-fatal warnings. A nil Logger discards them.
+	Logger hclog.Logger
+}
+
+func (p *HTTPPlugin) logger() hclog.Logger {
+	if p.Logger == nil {
+		return hclog.NewNullLogger()
+	}
+	return p.Logger
+}
 
 func (p *HTTPPlugin) Run(ctx context.Context, rawInput json.RawMessage) (json.RawMessage, error) {
 	var input PluginInput
 	if err := json.Unmarshal(rawInput, &input); err != nil {
 		return nil, fmt.Errorf("failed to parse input: %w", err)
 	}
+	// OAuth2 tokens are fetched once per Run and shared by every request it
+	// makes, such as the entries of a batch.
+	output, err := p.doRequest(withTokenCache(ctx), input)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(output)
+}
+
+// runWrapper runs the modes that send the probe as one or more entries,
+// such as 'requests'. It reports false when config is a single probe.
+func (p *HTTPPlugin) runWrapper(ctx context.Context, config map[string]string) (PluginOutput, bool, error) {
+	var run func(context.Context, map[string]string) (PluginOutput, error)
+	if _, ok := config["requiredConsecutiveSuccesses"]; ok {
+		run = p.runConsecutive
+	} else if _, ok := config["weightedUris"]; ok {
+		run = p.runWeighted
+	} else if config["fallbackUri"] != "" {
+		run = p.runWithFallback
+	} else if config["compareUri"] != "" {
+		run = p.runCompare
+	} else if _, ok := config["matrix"]; ok {
+		run = p.runMatrix
+	} else if _, ok := config["requests"]; ok {
+		run = p.runBatch
+	} else {
+		return PluginOutput{}, false, nil
+	}
+	output, err := run(ctx, config)
+	return output, true, err
+}
+
+// push reports the outcome of a Run to the pushgateway. A failed push is
+// only logged, as it says nothing about the probe.
+func (p *HTTPPlugin) push(ctx context.Context, g *pushgateway, success bool, status int, duration time.Duration) {
+	if err := g.push(ctx, success, status, duration); err != nil {
+		p.logger().Warn("failed to push result to pushgateway", "url", g.groupURL(), "error", err)
+	}
+}
+
+// doRequest runs the probe described by input and evaluates the result.
+// It holds all of Run's logic but none of the RPC encoding, so it can be
+// called directly, e.g. against an httptest.Server. Invalid configuration
+// is returned as an error; a failed probe is a PluginOutput with Success
+// set to false.
+func (p *HTTPPlugin) doRequest(ctx context.Context, input PluginInput) (output PluginOutput, err error) {
+	if input.Config["configFile"] != "" {
+		config, err := mergeConfigFile(input.Config)
+		if err != nil {
+			return PluginOutput{}, err
+		}
+		input.Config = config
+	}
+
+	phases, err := parsePhaseMapping(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	metric, err := parseArgoMetric(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	defer func() {
+		if err == nil {
+			output.Phase = phases.phase(output.Success)
+		}
+	}()
+
+	// Bound the whole invocation, across all retries and every request of
+	// a wrapper such as 'requests', when requested
+	budget, err := configDuration(input.Config, "maxTotalDuration", 0)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, budget, &errBudgetExhausted{budget: budget})
+		defer cancel()
+	}
+
+	// Assertions always see the whole body; only the message is cut
+	maxMessageBytes, err := configInt(input.Config, "maxMessageBytes", defaultMaxMessageBytes)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if maxMessageBytes < 0 {
+		return PluginOutput{}, fmt.Errorf("invalid 'maxMessageBytes' in config: must not be negative")
+	}
+	pushgateway, err := parsePushgateway(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	// Wrappers run the probe as entries, which do not report to the
+	// pushgateway or format a metric themselves; the Run as a whole is
+	// reported once here, with the combined message cut to
+	// 'maxMessageBytes'.
+	started := time.Now()
+	if result, wrapped, err := p.runWrapper(ctx, input.Config); wrapped {
+		if err != nil {
+			return PluginOutput{}, err
+		}
+		result.Message = truncateMessage(result.Message, maxMessageBytes)
+		if metric != nil {
+			metric.apply(&result, result.status, result.duration)
+		}
+		if pushgateway != nil {
+			p.push(ctx, pushgateway, result.Success, result.status, time.Since(started))
+		}
+		return result, nil
+	}
+
+	if key := firstKey(input.Config, batchKeys); key != "" {
+		return PluginOutput{}, fmt.Errorf("'%s' requires 'requests'", key)
+	}
+
+	grpcHealth, err := configBool(input.Config, "grpcHealth", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if grpcHealth {
+		// A gRPC check has no HTTP status, so it is reported as 0
+		outputFooter, err := configBool(input.Config, "outputFooter", false)
+		if err != nil {
+			return PluginOutput{}, err
+		}
+		result, err := p.runGRPCHealth(ctx, input.Config)
+		if err != nil {
+			return PluginOutput{}, err
+		}
+		duration := time.Since(started)
+		result.Message = truncateMessage(result.Message, maxMessageBytes)
+		if outputFooter {
+			result.Message = appendLine(result.Message, resultFooter(0, duration, result.Success))
+		}
+		if pushgateway != nil {
+			p.push(ctx, pushgateway, result.Success, 0, duration)
+		}
+		return result, nil
+	}
+
+	uri := configOrEnv(input.Config, "uri", "PLUGIN_DEFAULT_URI")
+	method := normalizeMethod(configOrEnv(input.Config, "method", "PLUGIN_DEFAULT_METHOD"))
+	if _, ok := input.Config["graphqlQuery"]; ok && method == "" {
+		method = http.MethodPost
+	}
+	if override := input.Config["methodOverride"]; override != "" {
+		if method, err = methodForOverride(input.Config, method, override); err != nil {
+			return PluginOutput{}, err
+		}
+	}
+	_, isRaw := input.Config["rawRequest"]
+	if uri == "" || (method == "" && !isRaw) {
+		return PluginOutput{}, fmt.Errorf("missing 'uri' or 'method' in config")
+	}
+	if !isRaw {
+		if err := checkMethod(input.Config, method); err != nil {
+			return PluginOutput{}, err
+		}
+	}
+
+	target, err := parseTargetURI(uri, input.Config["defaultScheme"])
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if input.Config["tlsServerName"] != "" && target.Scheme != "https" {
+		return PluginOutput{}, fmt.Errorf("'tlsServerName' requires an https 'uri'")
+	}
+
+	policy, err := parseRetryPolicy(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	mode, err := parseOutputMode(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	acceptNotModified, err := configBool(input.Config, "acceptNotModified", isConditional(input.Config))
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	headerMatch, err := parseHeaderMatch(input.Config, "headerMatch")
+	if err != nil {
+		return PluginOutput{}, err
+	}
 
-	uri, ok1 := input.Config["uri"]
-	method, ok2 := input.Config["method"]
-	if !ok1 || !ok2 {
-		return nil, fmt.Errorf("missing 'uri' or 'method' in config")
+	trailerMatch, err := parseHeaderMatch(input.Config, "trailerMatch")
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	includeTrailers, err := configBool(input.Config, "includeTrailers", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	certAssertions, err := parseTLSAssertions(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	cookieAssertions, err := parseCookieAssertions(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	var oauth2Config *clientcredentials.Config
+	if input.Config["authScheme"] == "oauth2" {
+		if oauth2Config, err = newOAuth2Config(input.Config); err != nil {
+			return PluginOutput{}, err
+		}
+	}
+	reauthOn401, err := configBool(input.Config, "reauthOn401", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if reauthOn401 && oauth2Config == nil {
+		return PluginOutput{}, fmt.Errorf("'reauthOn401' requires 'authScheme' oauth2")
+	}
+
+	requireProtocol, err := parseRequireProtocol(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	protocolVersion, err := parseProtocolVersion(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	serverTimingAssertion, err := parseServerTimingAssertion(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	matchLogic, err := parseMatchLogic(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	var expectedValue *valueMatcher
+	if raw, ok := input.Config["expectedValue"]; ok {
+		m, err := newValueMatcher(raw)
+		if err != nil {
+			return PluginOutput{}, fmt.Errorf("invalid 'expectedValue' pattern: %w", err)
+		}
+		expectedValue = &m
+	}
+
+	var jq *jqFilter
+	if raw, ok := input.Config["jq"]; ok {
+		if jq, err = compileJQ(raw); err != nil {
+			return PluginOutput{}, err
+		}
+	}
+
+	var xmlPath *xmlPathExpr
+	if raw, ok := input.Config["xmlPath"]; ok {
+		if xmlPath, err = compileXMLPath(raw); err != nil {
+			return PluginOutput{}, err
+		}
+	}
+
+	// Compiled up front, so a broken expression fails before any request
+	var celExpression *celGate
+	if raw, ok := input.Config["celExpression"]; ok {
+		if celExpression, err = compileCEL(raw); err != nil {
+			return PluginOutput{}, err
+		}
+	}
+
+	expectFailure, err := parseExpectFailure(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
+	judge, err := parseJudge(input.Config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return PluginOutput{}, err
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	var responseSchema *jsonschema.Schema
+	if raw, ok := input.Config["responseSchema"]; ok {
+		if responseSchema, err = compileResponseSchema(raw); err != nil {
+			return PluginOutput{}, err
+		}
+	}
+
+	timeout, err := configDuration(input.Config, "timeout", defaultTimeout)
 	if err != nil {
-		return json.Marshal(PluginOutput{
-			Message: fmt.Sprintf("Request error: %v", err),
-			Success: false,
-		})
+		return PluginOutput{}, err
+	}
+	if timeout <= 0 {
+		return PluginOutput{}, fmt.Errorf("invalid 'timeout' in config: must be positive")
+	}
+
+	bodySize, err := parseBodySize(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	expectNoRedirect, err := parseExpectNoRedirect(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	checkRedirect, err := newCheckRedirect(input.Config, expectNoRedirect)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	expectedRedirectCount, err := parseExpectedRedirectCount(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	bodyAnyOf, err := parseBodyAnyOf(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	var bodyMatch *regexp.Regexp
+	if raw, ok := input.Config["bodyMatch"]; ok {
+		if bodyMatch, err = regexp.Compile(raw); err != nil {
+			return PluginOutput{}, fmt.Errorf("invalid 'bodyMatch' pattern: %w", err)
+		}
+	}
+
+	sse, err := configBool(input.Config, "sse", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	closeOnMatch, err := configBool(input.Config, "closeOnMatch", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if closeOnMatch && bodyMatch == nil {
+		return PluginOutput{}, fmt.Errorf("'closeOnMatch' requires 'bodyMatch'")
+	}
+
+	// Content that is not ready yet is treated as a transient failure
+	retryOnBodyMismatch, err := configBool(input.Config, "retryOnBodyMismatch", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	jsonPath, hasJSONPath := input.Config["jsonPath"]
+	if retryOnBodyMismatch {
+		if bodyMatch == nil && !hasJSONPath {
+			return PluginOutput{}, fmt.Errorf("'retryOnBodyMismatch' requires 'bodyMatch' or 'jsonPath'")
+		}
+		if sse || closeOnMatch {
+			return PluginOutput{}, fmt.Errorf("'retryOnBodyMismatch' cannot be used with 'sse' or 'closeOnMatch'")
+		}
+	}
+
+	echo, err := configBool(input.Config, "echoRequest", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	pretty, err := configBool(input.Config, "prettyJson", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	latency, err := parseLatencyGate(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	verifyCorrelationEcho, err := configBool(input.Config, "verifyCorrelationEcho", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	paging, err := parsePagination(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if paging != nil && (sse || closeOnMatch) {
+		// Every page is read to the end to find the next link
+		return PluginOutput{}, fmt.Errorf("'followPagination' cannot be used with 'sse' or 'closeOnMatch'")
+	}
+	if (trailerMatch != nil || includeTrailers) && (sse || closeOnMatch) {
+		// Neither reads the body to the end, where the trailers are
+		return PluginOutput{}, fmt.Errorf("'trailerMatch' and 'includeTrailers' cannot be used with 'sse' or 'closeOnMatch'")
+	}
+
+	warmups, err := configInt(input.Config, "warmupRequests", 0)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	load, err := parseLoadTest(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if sse && (warmups > 0 || latency.samples > 0 || load != nil) {
+		// Their responses are read to the end, which an event stream never reaches
+		return PluginOutput{}, fmt.Errorf("'warmupRequests', 'samples' and 'load' cannot be used with 'sse'")
+	}
+
+	failOnEmptyBody, err := configBool(input.Config, "failOnEmptyBody", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	outputFooter, err := configBool(input.Config, "outputFooter", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	delay, err := parseStartDelay(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	responseFileRequired, err := configBool(input.Config, "responseFileRequired", false)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	// Transports are shared across runs so that frequent probes reuse
+	// connections; the cache closes them once they go unused.
+	transport, err := transports.get(input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if cancelURL := input.Config["cancelUrl"]; cancelURL != "" {
+		if _, err := url.ParseRequestURI(cancelURL); err != nil {
+			return PluginOutput{}, fmt.Errorf("invalid 'cancelUrl' in config: %w", err)
+		}
+		interval, err := configDuration(input.Config, "cancelPollInterval", defaultCancelPollInterval)
+		if err != nil {
+			return PluginOutput{}, err
+		}
+		if interval <= 0 {
+			return PluginOutput{}, fmt.Errorf("invalid 'cancelPollInterval' in config: must be positive")
+		}
+		go watchCancelURL(ctx, cancelURL, interval, cancel)
+	}
+
+	ctx, span := startRunSpan(ctx, method, target)
+	defer span.End()
+
+	// finish records the outcome of a probe that was sent, then returns it
+	finish := func(result PluginOutput, status int, duration time.Duration) (PluginOutput, error) {
+		result.Message = truncateMessage(result.Message, maxMessageBytes)
+		if outputFooter {
+			result.Message = appendLine(result.Message, resultFooter(status, duration, result.Success))
+		}
+		if metric != nil {
+			metric.apply(&result, status, duration)
+		}
+		endRunSpan(span, status, duration, result)
+		if pushgateway != nil {
+			p.push(ctx, pushgateway, result.Success, status, duration)
+		}
+		result.status, result.duration = status, duration
+		return result, nil
+	}
+
+	// Whether the probe got a pooled connection, for 'requireConnectionReuse'.
+	// Load and latency requests share ctx and may run concurrently.
+	var connReused atomic.Bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { connReused.Store(info.Reused) },
+	})
+
+	req, err := buildRequest(ctx, input.Config, method, target)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if protocolVersion == "1.0" {
+		req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/1.0", 1, 0
+		req.Close = true
+	}
+	correlationHeader, correlationID, err := applyCorrelationID(req, input.Config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	if correlationID != "" {
+		p.logger().Info("sending probe", "method", req.Method, "uri", target.Redacted(), "correlationId", correlationID)
+	}
+	if req.Method == http.MethodHead {
+		if key := firstKey(input.Config, bodyAssertionKeys); key != "" {
+			return PluginOutput{}, fmt.Errorf("'%s' cannot be used with HEAD requests, which have no body", key)
+		}
+	}
+
+	// The delay happens before the clock for the reported duration starts
+	if err := delay.wait(ctx); err != nil {
+		return errorOutput(mode, fmt.Errorf("start delay interrupted: %w", err)), nil
+	}
+
+	release, err := acquireRunSlot(ctx)
+	if err != nil {
+		return errorOutput(mode, err), nil
+	}
+	defer release()
+
+	client := &http.Client{Timeout: timeout, Transport: transport, CheckRedirect: checkRedirect}
+	if protocolVersion == "1.0" {
+		client.Transport = &http10Transport{transport: transport}
+	}
+	switch input.Config["authScheme"] {
+	case "ntlm":
+		client.Transport = &ntlmTransport{next: client.Transport}
+	case "oauth2":
+		client.Transport = &oauth2Transport{next: client.Transport, host: target.Host, cfg: oauth2Config, cache: tokenCacheFrom(ctx), reauth: reauthOn401}
+	}
+	if rateLimiter != nil {
+		client.Transport = &rateLimitedTransport{limiter: rateLimiter, next: client.Transport}
+	}
+	if tracingEnabled {
+		client.Transport = &tracingTransport{next: client.Transport}
+	}
+	if err := warmUp(ctx, client, req, warmups); err != nil {
+		return errorOutput(mode, fmt.Errorf("warm-up interrupted: %w", err)), nil
+	}
+
+	// Only the probe itself waits for its body; other requests, such as
+	// further pages, keep the plain policy
+	probePolicy := policy
+	if retryOnBodyMismatch {
+		probePolicy.bodyMismatch = func(resp *http.Response, body []byte) bool {
+			if !statusCriterion(resp, acceptNotModified).passed {
+				return false
+			}
+			if bodyMatch != nil && !bodyMatchCriterion(bodyMatch, body).passed {
+				return true
+			}
+			return hasJSONPath && !jsonPathCriterion(jsonPath, expectedValue, body).passed
+		}
+	}
+
+	start := time.Now()
+	resp, attempts, err := doWithRetryCount(ctx, client, probePolicy, req)
+	if err != nil {
+		var cancelled *errCancelled
+		var exhausted *errBudgetExhausted
+		cause := context.Cause(ctx)
+		switch {
+		case errors.As(cause, &cancelled):
+			err = cancelled
+		case errors.As(cause, &exhausted):
+			err = fmt.Errorf("%w: %v", exhausted, err)
+		}
+		result := errorOutput(mode, err)
+		if expectFailure != nil && cause == nil && expectFailure.connectionFailed(err) {
+			// A negative check: the endpoint is expected to be gone
+			result = PluginOutput{Success: true}
+			if mode != outputModeMinimal {
+				result.Message = fmt.Sprintf("Request failed as expected: %v", err)
+			}
+		}
+		result.CorrelationID = correlationID
+		if echo {
+			result.RequestEcho = echoRequest(req, configList(input.Config, "secretHeaders"))
+		}
+		if policy.duration > 0 || retryOnBodyMismatch {
+			result.reportRetries(mode, attempts, time.Since(start))
+		}
+		return finish(result, 0, time.Since(start))
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	// HEAD responses carry no body, and some servers misbehave when one is
+	// read, so only the status and headers are evaluated.
+	var respBody []byte
+	var readErr error
+	switch {
+	case req.Method == http.MethodHead:
+	case sse:
+		// Only the first event is read; closing the body ends the stream
+		respBody, readErr = readFirstSSEEvent(resp.Body, sseLineLimit(bodySize))
+	case closeOnMatch:
+		// Closing the body before EOF aborts the request and drops the
+		// connection, so streaming endpoints are not held open
+		respBody, readErr = readUntilMatch(resp.Body, bodyMatch)
+		resp.Body.Close()
+	default:
+		respBody, readErr = io.ReadAll(resp.Body)
+	}
+	duration := time.Since(start)
+	displayBody := respBody
+	if patterns := configList(input.Config, "captureBodyForContentTypes"); len(patterns) > 0 {
+		displayBody = capturedBody(patterns, resp.Header.Get("Content-Type"), respBody)
+	}
+	if pretty {
+		displayBody = prettyJSON(resp.Header.Get("Content-Type"), displayBody)
+	}
 	result := PluginOutput{
-		Message: fmt.Sprintf("Status: %s\nBody: %s", resp.Status, string(body)),
-		Success: resp.StatusCode >= 200 && resp.StatusCode < 300,
+		Message:       formatResponseMessage(mode, resp, displayBody, duration),
+		ContentType:   resp.Header.Get("Content-Type"),
+		Compressed:    wasCompressed(resp),
+		CorrelationID: correlationID,
+		Protocol:      resp.Proto,
+		TLS:           newTLSInfo(resp.TLS, time.Now()),
+		ServerTimings: parseServerTimings(resp.Header),
+	}
+	if sse && readErr == nil {
+		result.FirstEvent = string(respBody)
+	}
+	result.body, result.status, result.header = respBody, resp.StatusCode, resp.Header
+	result.reused = connReused.Load()
+	if includeTrailers {
+		result.Trailers = describeTrailers(resp.Trailer)
+		if mode != outputModeMinimal && len(result.Trailers) > 0 {
+			result.Message += "\nTrailers: " + formatTrailers(result.Trailers)
+		}
+	}
+	if echo {
+		// resp.Request is the last request sent, after any redirects
+		result.RequestEcho = echoRequest(resp.Request, configList(input.Config, "secretHeaders"))
+	}
+	if resp.ContentLength >= 0 {
+		result.ContentLength = resp.ContentLength
+	}
+	result.RedirectCount = len(redirectChain(resp)) - 1
+	if req.Method != http.MethodHead {
+		sum := sha256.Sum256(respBody)
+		result.BodySha256 = hex.EncodeToString(sum[:])
+	}
+	if policy.duration > 0 || retryOnBodyMismatch {
+		result.reportRetries(mode, attempts, duration)
+	}
+	if isConditional(input.Config) && mode != outputModeMinimal {
+		result.Message += "\n" + describeConditional(resp)
 	}
 
-	return json.Marshal(result)
+	// A CEL expression or judge decides on the status itself
+	checks := criteria{statusCriterion(resp, acceptNotModified)}
+	if celExpression != nil {
+		checks = criteria{celExpression.criterion(resp, respBody, duration)}
+	}
+	if expectFailure != nil {
+		checks = criteria{expectFailure.criterion(resp)}
+	}
+	if judge != nil {
+		checks = criteria{judge.criterion(ctx, resp, respBody, duration)}
+	}
+	if expectNoRedirect {
+		checks = append(checks, noRedirectCriterion(resp))
+	}
+	if expectedRedirectCount >= 0 {
+		checks = append(checks, redirectCountCriterion(expectedRedirectCount, redirectChain(resp)))
+	}
+	if requireProtocol != "" {
+		checks = append(checks, protocolCriterion(requireProtocol, resp))
+	}
+	if certAssertions != nil {
+		checks = append(checks, certAssertions.criterion(result.TLS))
+	}
+	if cookieAssertions != nil {
+		result.Cookies = cookieAssertions.cookies(resp)
+		checks = append(checks, cookieAssertions.criterion(result.Cookies))
+	}
+	if serverTimingAssertion != nil {
+		checks = append(checks, serverTimingAssertion.criterion(result.ServerTimings))
+	}
+	if headerMatch != nil {
+		checks = append(checks, headerMatch.criterion("headerMatch", "header", resp.Header))
+	}
+	if trailerMatch != nil {
+		// Trailers are only known once the body has been read to the end
+		checks = append(checks, trailerMatch.criterion("trailerMatch", "trailer", resp.Trailer))
+	}
+	if absent := configList(input.Config, "headerAbsent"); len(absent) > 0 {
+		checks = append(checks, headerAbsentCriterion(absent, resp.Header))
+	}
+	if bodyMatch != nil {
+		checks = append(checks, bodyMatchCriterion(bodyMatch, respBody))
+	}
+	if bodyAnyOf != nil {
+		checks = append(checks, bodyAnyOf.criterion(respBody))
+	}
+	if bodySize != nil {
+		checks = append(checks, bodySize.criterion(len(respBody)))
+	}
+	if expected, ok := input.Config["expectedSha256"]; ok {
+		checks = append(checks, sha256Criterion(expected, result.BodySha256))
+	}
+	if path, ok := input.Config["jsonPath"]; ok {
+		checks = append(checks, jsonPathCriterion(path, expectedValue, respBody))
+	}
+	if jq != nil {
+		checks = append(checks, jq.criterion(ctx, expectedValue, respBody))
+	}
+	if xmlPath != nil {
+		checks = append(checks, xmlPath.criterion(expectedValue, respBody))
+	}
+	if responseSchema != nil {
+		checks = append(checks, schemaCriterion(responseSchema, respBody))
+	}
+	if verifyCorrelationEcho {
+		checks = append(checks, correlationEchoCriterion(correlationHeader, correlationID, resp.Header))
+	}
+	if paging != nil && readErr == nil {
+		pages := paging.follow(ctx, client, policy, req, resp, respBody)
+		result.Pages, result.TotalItems = pages.pages, pages.items
+		checks = append(checks, paging.criterion(pages))
+	}
+	if latency.samples > 0 {
+		report, err := latency.sample(ctx, client, req)
+		if err != nil {
+			result := errorOutput(mode, fmt.Errorf("latency sampling interrupted: %w", err))
+			return finish(result, resp.StatusCode, duration)
+		}
+		result.Latency = report
+		checks = append(checks, latency.criterion(report))
+	}
+	if load != nil {
+		report, err := load.run(ctx, client, req, acceptNotModified)
+		if err != nil {
+			result := errorOutput(mode, fmt.Errorf("load run interrupted: %w", err))
+			return finish(result, resp.StatusCode, duration)
+		}
+		result.Load = report
+		checks = append(checks, load.criterion(report))
+	}
+	result.Success = checks.passed(matchLogic)
+	// The criteria are listed unless the plain status check is all there is
+	if (len(checks) > 1 || checks[0].name != "status") && mode != outputModeMinimal {
+		result.Message += "\n" + checks.describe(matchLogic)
+	}
+
+	// A truncated body invalidates the response regardless of its status
+	if readErr != nil {
+		result.Success = false
+		result.FailureReason = fmt.Sprintf("body read failed after %d bytes: %v", len(respBody), readErr)
+		if mode != outputModeMinimal {
+			result.Message += "\nBody read error: " + result.FailureReason
+		}
+	} else if failOnEmptyBody && len(respBody) == 0 {
+		// Some load balancers answer 200 with nothing when the backend is gone
+		result.Success = false
+		result.FailureReason = fmt.Sprintf("empty response body (status %d)", resp.StatusCode)
+		if mode != outputModeMinimal {
+			result.Message += "\nEmpty body: " + result.FailureReason
+		}
+	}
+
+	if path := input.Config["responseFile"]; path != "" {
+		if err := os.WriteFile(path, respBody, 0o644); err != nil {
+			if responseFileRequired {
+				result.Success = false
+				if mode != outputModeMinimal {
+					result.Message += fmt.Sprintf("\nResponse file error: %v", err)
+				}
+			} else {
+				p.logger().Warn("failed to write response file", "path", path, "error", err)
+			}
+		}
+	}
+
+	return finish(result, resp.StatusCode, duration)
 }
 
 // ---- Plugin Wrapping ----
@@ -100,7 +935,7 @@ func (m *RPCClient) Run(ctx context.Context, rawInput json.RawMessage) (json.Raw
 	var resp json.RawMessage
 	err := m.client.Call("Plugin.Run", rawInput, &resp)
 	if err != nil {
-		return nil, err
+		return nil, decodeRPCError(err)
 	}
 	return resp, nil
 }
@@ -111,34 +946,113 @@ type RPCServer struct {
 	Impl StepPlugin
 }
 
-func (m *RPCServer) Run(rawInput json.RawMessage, resp *json.RawMessage) error {
+// Run returns errors, including recovered panics, as an encoded RPCError.
+func (m *RPCServer) Run(rawInput json.RawMessage, resp *json.RawMessage) (err error) {
+	defer recoverRPCPanic(&err)
 	result, err := m.Impl.Run(context.Background(), rawInput)
 	if err != nil {
-		return err
+		return encodeRPCError(rpcErrorInvalidInput, err)
 	}
 	*resp = result
 	return nil
 }
 
 // ---- Main Entrypoint ----
+
+// setupProcess applies the environment settings shared by the plugin
+// server and the stdin mode: the outbound rate limit, the cap on
+// concurrent runs and tracing. The returned func flushes pending spans.
+func setupProcess() (func(context.Context) error, error) {
+	limiter, err := newRateLimiterFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure rate limiter: %w", err)
+	}
+	rateLimiter = limiter
+
+	slots, err := newRunSlotsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure concurrency limit: %w", err)
+	}
+	runSlots = slots
+
+	// Export spans over OTLP when tracing is enabled
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure tracing: %w", err)
+	}
+	return shutdownTracing, nil
+}
+
+// newLogger returns the plugin logger, which writes to stderr so that
+// stdout stays free for the plugin handshake or the stdin mode's output.
+func newLogger() hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:   "plugin",
+		Output: os.Stderr,
+		Level:  hclog.Debug,
+	})
+}
+
 func main() {
 	// Set up logging to stderr with timestamps
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.SetOutput(os.Stderr)
 
+	selfTest := flag.Bool("selftest", false, "run the built-in self-test and exit")
+	stdin := flag.Bool("stdin", false, "run the PluginInput JSON read from stdin, print the PluginOutput JSON and exit")
+	flag.Parse()
+	if *selfTest {
+		if !runSelfTest(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+	if *stdin {
+		shutdownTracing, err := setupProcess()
+		if err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+		// Ctrl-C cancels the probe like an aborted rollout would
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		success, err := runStdin(ctx, newLogger(), os.Stdin, os.Stdout)
+		stop()
+		shutdownTracing(context.Background())
+		if err != nil {
+			log.Printf("Invalid input: %v", err)
+			os.Exit(2)
+		}
+		if !success {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Log startup information
 	log.Printf("Starting plugin with handshake config: %+v", handshake)
 
+	shutdownTracing, err := setupProcess()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Optionally expose a liveness endpoint for the plugin process
+	if addr := os.Getenv(healthAddrEnv); addr != "" {
+		if err := startHealthServer(addr); err != nil {
+			log.Fatalf("Failed to start health server on %s: %v", addr, err)
+		}
+		log.Printf("Serving health endpoint on %s/healthz", addr)
+	}
+
+	logger := newLogger()
+
 	// Create plugin server
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: handshake,
 		Plugins: map[string]plugin.Plugin{
-			"step": &HTTPStepPlugin{Impl: &HTTPPlugin{}},
+			"step": &HTTPStepPlugin{Impl: &HTTPPlugin{Logger: logger}},
 		},
-		Logger: hclog.New(&hclog.LoggerOptions{
-			Name:   "plugin",
-			Output: os.Stderr,
-			Level:  hclog.Debug,
-		}),
+		Logger: logger,
 	})
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -119,3 +121,53 @@ func TestPluginIntegration(t *testing.T) {
 		})
 	}
 }
+
+// runPlugin invokes HTTPPlugin.Run in-process with the given config and
+// decodes its output.
+func runPlugin(t *testing.T, config map[string]string) (PluginOutput, error) {
+	t.Helper()
+
+	inputJSON, err := json.Marshal(PluginInput{Config: config})
+	if err != nil {
+		t.Fatalf("Failed to marshal input: %v", err)
+	}
+
+	result, err := (&HTTPPlugin{}).Run(context.Background(), inputJSON)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	var output PluginOutput
+	if err := json.Unmarshal(result, &output); err != nil {
+		t.Fatalf("Failed to unmarshal output: %v", err)
+	}
+	return output, nil
+}
+
+func TestDoRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := map[string]string{"uri": server.URL, "method": "GET"}
+	direct, err := (&HTTPPlugin{}).doRequest(context.Background(), PluginInput{Config: config})
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	if !direct.Success {
+		t.Fatalf("expected success, got %+v", direct)
+	}
+
+	viaRun, err := runPlugin(t, config)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if direct.Success != viaRun.Success || direct.Message != viaRun.Message {
+		t.Errorf("doRequest and Run disagree: %+v vs %+v", direct, viaRun)
+	}
+
+	if _, err := (&HTTPPlugin{}).doRequest(context.Background(), PluginInput{Config: map[string]string{"uri": server.URL}}); err == nil {
+		t.Error("expected a config error for a missing method")
+	}
+}
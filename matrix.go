@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ---- Probe Matrix ----
+
+// maxMatrixVariants bounds the number of requests a 'matrix' expands to.
+const maxMatrixVariants = 64
+
+// matrixVariant is one combination of matrix values merged over the
+// top-level config. label lists the combination as "key=value" pairs.
+type matrixVariant struct {
+	config map[string]string
+	label  string
+}
+
+// parseMatrix expands the 'matrix' object, which maps config keys to lists
+// of values, into the cartesian product of those values. Keys vary in
+// alphabetical order, the last one fastest. As in batch mode, values may
+// be strings or any JSON value.
+func parseMatrix(config map[string]string) ([]matrixVariant, error) {
+	if _, ok := config["requests"]; ok {
+		return nil, fmt.Errorf("'matrix' cannot be combined with 'requests'")
+	}
+	var dimensions map[string][]json.RawMessage
+	if err := json.Unmarshal([]byte(config["matrix"]), &dimensions); err != nil {
+		return nil, fmt.Errorf("invalid 'matrix' in config: %w", err)
+	}
+	if len(dimensions) == 0 {
+		return nil, fmt.Errorf("invalid 'matrix' in config: must contain at least one key")
+	}
+
+	keys := make([]string, 0, len(dimensions))
+	size := 1
+	for key, values := range dimensions {
+		if key == "matrix" || key == "requests" {
+			return nil, fmt.Errorf("invalid 'matrix' in config: '%s' cannot vary", key)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("invalid 'matrix' in config: '%s' has no values", key)
+		}
+		size *= len(values)
+		if size > maxMatrixVariants {
+			return nil, fmt.Errorf("invalid 'matrix' in config: expands to more than %d requests", maxMatrixVariants)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	variants := make([]matrixVariant, size)
+	for i := range variants {
+		m := make(map[string]string, len(config)+len(keys))
+		for key, value := range config {
+			if key != "matrix" {
+				m[key] = value
+			}
+		}
+		// Decompose i into one index per key, the last key varying fastest
+		labels := make([]string, len(keys))
+		rest := i
+		for k := len(keys) - 1; k >= 0; k-- {
+			values := dimensions[keys[k]]
+			value, err := batchValue(values[rest%len(values)])
+			if err != nil {
+				return nil, fmt.Errorf("invalid 'matrix' in config: '%s': %w", keys[k], err)
+			}
+			m[keys[k]] = value
+			labels[k] = keys[k] + "=" + value
+			rest /= len(values)
+		}
+		variants[i] = matrixVariant{config: m, label: strings.Join(labels, " ")}
+	}
+	return variants, nil
+}
+
+// runMatrix runs every variant of the matrix in order. The step succeeds
+// only when all of them do; the message has one summary line per variant,
+// unless the output is minimal.
+func (p *HTTPPlugin) runMatrix(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	variants, err := parseMatrix(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	mode, err := parseOutputMode(config)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	result := PluginOutput{Success: true}
+	var lines []string
+	passed := 0
+	for i, variant := range variants {
+		output, err := p.runEntry(ctx, variant.config)
+		if err != nil {
+			return PluginOutput{}, fmt.Errorf("matrix variant %s: %w", variant.label, err)
+		}
+		outcome := "PASS"
+		if output.Success {
+			passed++
+		} else {
+			outcome = "FAIL"
+			if result.Success {
+				result.Success = false
+				result.FailureReason = fmt.Sprintf("matrix variant %d (%s) failed", i, variant.label)
+				if output.FailureReason != "" {
+					result.FailureReason += ": " + output.FailureReason
+				}
+			}
+		}
+		line := fmt.Sprintf("[%s] %s", outcome, variant.label)
+		if status := messageStatus(output.Message); status != "" {
+			line += " (" + status + ")"
+		}
+		lines = append(lines, line)
+	}
+	if mode != outputModeMinimal {
+		result.Message = fmt.Sprintf("Matrix: %d/%d variants passed\n%s", passed, len(variants), strings.Join(lines, "\n"))
+	}
+	return result, nil
+}
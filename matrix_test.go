@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/legacy" && r.Header.Get("Accept") == "application/json" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Team")))
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":     server.URL + "/api",
+		"method":  "GET",
+		"headers": `{"X-Team": "canary"}`,
+		"matrix":  `{"uri": ["` + server.URL + `/api", "` + server.URL + `/legacy"], "accept": ["text/html", "application/json"]}`,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success {
+		t.Error("Expected the matrix to fail on the /legacy JSON variant")
+	}
+	want := fmt.Sprintf(`Matrix: 3/4 variants passed
+[PASS] accept=text/html uri=%[1]s/api (200 OK)
+[PASS] accept=text/html uri=%[1]s/legacy (200 OK)
+[PASS] accept=application/json uri=%[1]s/api (200 OK)
+[FAIL] accept=application/json uri=%[1]s/legacy (406 Not Acceptable)`, server.URL)
+	if output.Message != want {
+		t.Errorf("Message = %q, want %q", output.Message, want)
+	}
+	if !strings.HasPrefix(output.FailureReason, "matrix variant 3 (accept=application/json uri="+server.URL+"/legacy) failed") {
+		t.Errorf("FailureReason = %q", output.FailureReason)
+	}
+
+	output, err = runPlugin(t, map[string]string{
+		"method":     "GET",
+		"outputMode": "minimal",
+		"matrix":     `{"uri": ["` + server.URL + `/api", "` + server.URL + `/legacy"]}`,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.Message != "" {
+		t.Errorf("Expected an empty message with outputMode minimal, got %+v", output)
+	}
+}
+
+func TestMatrixConfig(t *testing.T) {
+	values := make([]string, maxMatrixVariants/2+1)
+	for i := range values {
+		values[i] = fmt.Sprintf(`"%d"`, i)
+	}
+	tooLarge := `{"timeout": ["1s", "2s"], "retries": [` + strings.Join(values, ",") + `]}`
+
+	for _, matrix := range []string{
+		`not json`,
+		`{}`,
+		`{"uri": []}`,
+		`{"matrix": ["x"]}`,
+		tooLarge,
+	} {
+		config := map[string]string{"uri": "http://127.0.0.1:1", "method": "GET", "matrix": matrix}
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for matrix %s", matrix)
+		}
+	}
+
+	if _, err := runPlugin(t, map[string]string{"matrix": `{"method": ["GET"]}`, "requests": `[{"uri": "http://127.0.0.1:1"}]`}); err == nil {
+		t.Error("Expected error for 'matrix' combined with 'requests'")
+	}
+}
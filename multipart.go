@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+)
+
+// ---- Multipart Bodies ----
+
+// multipartPart describes one part of a multipart/form-data body. Parts with
+// a filename are sent as file parts; the others are sent as plain fields.
+// Content is taken either inline from Content or from the file at Path.
+type multipartPart struct {
+	Name     string `json:"name"`
+	Filename string `json:"filename,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// buildMultipartBody encodes the JSON list of parts in raw into a
+// multipart/form-data body and returns it with its Content-Type. An empty
+// list produces a valid body holding only the closing boundary.
+func buildMultipartBody(raw string) (*bytes.Buffer, string, error) {
+	var parts []multipartPart
+	if err := json.Unmarshal([]byte(raw), &parts); err != nil {
+		return nil, "", fmt.Errorf("invalid 'multipart' in config: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for i, part := range parts {
+		if part.Name == "" {
+			return nil, "", fmt.Errorf("multipart part %d is missing 'name'", i)
+		}
+		if part.Content != "" && part.Path != "" {
+			return nil, "", fmt.Errorf("multipart part %q sets both 'content' and 'path'", part.Name)
+		}
+
+		var dst io.Writer
+		var err error
+		if part.Filename != "" {
+			dst, err = writer.CreateFormFile(part.Name, part.Filename)
+		} else {
+			dst, err = writer.CreateFormField(part.Name)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create multipart part %q: %w", part.Name, err)
+		}
+
+		if part.Path == "" {
+			_, err = io.WriteString(dst, part.Content)
+		} else {
+			err = copyFile(dst, part.Path)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to write multipart part %q: %w", part.Name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to finalize multipart body: %w", err)
+	}
+
+	return body, writer.FormDataContentType(), nil
+}
+
+func copyFile(dst io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(dst, f)
+	return err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payload.txt")
+	if err := os.WriteFile(path, []byte("from disk"), 0o600); err != nil {
+		t.Fatalf("Failed to write payload: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got := r.FormValue("greeting"); got != "hello" {
+			http.Error(w, "unexpected greeting: "+got, http.StatusBadRequest)
+			return
+		}
+		for name, want := range map[string]string{"inline": "inline data", "disk": "from disk"} {
+			f, _, err := r.FormFile(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			got, _ := io.ReadAll(f)
+			f.Close()
+			if string(got) != want {
+				http.Error(w, "unexpected "+name+" content: "+string(got), http.StatusBadRequest)
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":    server.URL,
+		"method": "POST",
+		"multipart": `[
+			{"name": "greeting", "content": "hello"},
+			{"name": "inline", "filename": "inline.txt", "content": "inline data"},
+			{"name": "disk", "filename": "payload.txt", "path": "` + path + `"}
+		]`,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected successful response, got: %v", output.Message)
+	}
+}
+
+func TestMultipartEmptyBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "POST", "multipart": "[]"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected successful response, got: %v", output.Message)
+	}
+}
+
+func TestMultipartInvalidConfig(t *testing.T) {
+	for _, raw := range []string{
+		`{"name": "not a list"}`,
+		`[{"content": "no name"}]`,
+		`[{"name": "both", "content": "x", "path": "/tmp/x"}]`,
+		`[{"name": "missing", "filename": "f", "path": "/does/not/exist"}]`,
+	} {
+		if _, err := runPlugin(t, map[string]string{"uri": "http://127.0.0.1", "method": "POST", "multipart": raw}); err == nil {
+			t.Errorf("Expected error for multipart %s", raw)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// ---- OAuth2 Client Credentials ----
+
+// newOAuth2Config returns the client credentials grant for 'authScheme'
+// oauth2.
+func newOAuth2Config(config map[string]string) (*clientcredentials.Config, error) {
+	tokenURL, id, secret := config["oauth2TokenUrl"], config["oauth2ClientId"], config["oauth2ClientSecret"]
+	if tokenURL == "" || id == "" || secret == "" {
+		return nil, fmt.Errorf("'authScheme' oauth2 requires 'oauth2TokenUrl', 'oauth2ClientId' and 'oauth2ClientSecret'")
+	}
+	return &clientcredentials.Config{
+		ClientID:     id,
+		ClientSecret: secret,
+		TokenURL:     tokenURL,
+		Scopes:       configList(config, "oauth2Scopes"),
+	}, nil
+}
+
+type tokenCacheKey struct{}
+
+// tokenCache holds the access tokens fetched during one Run, so that the
+// requests of a batch or of repeated attempts authenticate only once.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*oauth2.Token
+}
+
+// withTokenCache scopes a new token cache to ctx.
+func withTokenCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tokenCacheKey{}, &tokenCache{tokens: make(map[string]*oauth2.Token)})
+}
+
+// tokenCacheFrom returns the cache scoped to ctx, or a fresh one.
+func tokenCacheFrom(ctx context.Context) *tokenCache {
+	if cache, ok := ctx.Value(tokenCacheKey{}).(*tokenCache); ok {
+		return cache
+	}
+	return &tokenCache{tokens: make(map[string]*oauth2.Token)}
+}
+
+func cacheKey(cfg *clientcredentials.Config) string {
+	return strings.Join([]string{cfg.TokenURL, cfg.ClientID, cfg.ClientSecret, strings.Join(cfg.Scopes, " ")}, "\x00")
+}
+
+// token returns a valid cached token for cfg, fetching one with client
+// when there is none.
+func (c *tokenCache) token(ctx context.Context, cfg *clientcredentials.Config, client *http.Client) (*oauth2.Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(cfg)
+	if tok := c.tokens[key]; tok.Valid() {
+		return tok, nil
+	}
+	tok, err := cfg.Token(context.WithValue(ctx, oauth2.HTTPClient, client))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OAuth2 token: %w", err)
+	}
+	c.tokens[key] = tok
+	return tok, nil
+}
+
+// invalidate drops tok, unless another request already replaced it.
+func (c *tokenCache) invalidate(cfg *clientcredentials.Config, tok *oauth2.Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key := cacheKey(cfg); c.tokens[key] == tok {
+		delete(c.tokens, key)
+	}
+}
+
+// oauth2Transport authorizes requests to host with a token from the
+// cache; requests to other hosts, such as a redirect or next page
+// elsewhere, are sent without it. With reauth, a 401 drops the token and
+// the request is retried once with a fresh one.
+type oauth2Transport struct {
+	next   http.RoundTripper
+	host   string
+	cfg    *clientcredentials.Config
+	cache  *tokenCache
+	reauth bool
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.next.RoundTrip(req)
+	}
+	// Tokens are fetched over the same transport, so they honour the TLS
+	// and proxy settings of the probe.
+	client := &http.Client{Transport: t.next}
+	tok, err := t.cache.token(req.Context(), t.cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req, tok)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !t.reauth {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// The body was consumed and cannot be sent again
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	t.cache.invalidate(t.cfg, tok)
+	if tok, err = t.cache.token(req.Context(), t.cfg, client); err != nil {
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+	}
+	return t.send(retry, tok)
+}
+
+func (t *oauth2Transport) send(req *http.Request, tok *oauth2.Token) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	tok.SetAuthHeader(req)
+	return t.next.RoundTrip(req)
+}
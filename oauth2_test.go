@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOAuth2TokenReuse(t *testing.T) {
+	var issued, rejected atomic.Int32
+	var revoked atomic.Value
+	revoked.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if id, secret, _ := r.BasicAuth(); id != "probe" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": 3600}`, issued.Add(1))
+		default:
+			// The server rejects the first token, as after a key rotation
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer token-") || auth == "Bearer "+revoked.Load().(string) {
+				rejected.Add(1)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	base := map[string]string{
+		"method":             "GET",
+		"authScheme":         "oauth2",
+		"oauth2TokenUrl":     server.URL + "/token",
+		"oauth2ClientId":     "probe",
+		"oauth2ClientSecret": "s3cret",
+	}
+	with := func(extra map[string]string) map[string]string {
+		config := make(map[string]string)
+		for k, v := range base {
+			config[k] = v
+		}
+		for k, v := range extra {
+			config[k] = v
+		}
+		return config
+	}
+
+	requests, _ := json.Marshal([]map[string]string{{"uri": server.URL + "/a"}, {"uri": server.URL + "/b"}, {"uri": server.URL + "/c"}})
+	output, err := runPlugin(t, with(map[string]string{"requests": string(requests)}))
+	if err != nil || !output.Success {
+		t.Fatalf("Expected the batch to succeed, got %v %+v", err, output)
+	}
+	if n := issued.Load(); n != 1 {
+		t.Errorf("Issued %d tokens for the batch, want 1", n)
+	}
+
+	// A new run authenticates again
+	issued.Store(0)
+	revoked.Store("token-1")
+	output, err = runPlugin(t, with(map[string]string{"uri": server.URL}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "401") {
+		t.Errorf("Expected a 401 without 'reauthOn401', got %+v", output)
+	}
+
+	issued.Store(0)
+	rejected.Store(0)
+	output, err = runPlugin(t, with(map[string]string{"uri": server.URL, "method": "POST", "body": "payload", "reauthOn401": "true"}))
+	if err != nil || !output.Success {
+		t.Fatalf("Expected a refreshed token to succeed, got %v %+v", err, output)
+	}
+	if issued.Load() != 2 || rejected.Load() != 1 {
+		t.Errorf("Issued %d tokens with %d rejections, want 2 and 1", issued.Load(), rejected.Load())
+	}
+	if !strings.Contains(output.Message, "payload") {
+		t.Errorf("Expected the body to be resent on retry, got %q", output.Message)
+	}
+
+	// A redirect to another host does not get the token
+	var leaked string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization")
+	}))
+	defer other.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(other.URL, http.StatusFound))
+	defer redirect.Close()
+	output, err = runPlugin(t, with(map[string]string{"uri": redirect.URL}))
+	if err != nil || !output.Success || leaked != "" {
+		t.Errorf("Expected the other host to get no token, got %q: %v %+v", leaked, err, output)
+	}
+
+	output, err = runPlugin(t, with(map[string]string{"uri": server.URL, "oauth2ClientSecret": "wrong"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "failed to fetch OAuth2 token") {
+		t.Errorf("Expected the token error to be reported, got %+v", output)
+	}
+
+	for _, config := range []map[string]string{
+		with(map[string]string{"uri": server.URL, "oauth2TokenUrl": ""}),
+		{"uri": server.URL, "method": "GET", "reauthOn401": "true"},
+		with(map[string]string{"uri": server.URL, "reauthOn401": "maybe"}),
+	} {
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ---- Output Formatting ----
+
+// Output modes accepted by the 'outputMode' key.
+const (
+	outputModeFull    = "full"
+	outputModeSummary = "summary"
+	outputModeMinimal = "minimal"
+)
+
+func parseOutputMode(config map[string]string) (string, error) {
+	switch mode := config["outputMode"]; mode {
+	case "":
+		return outputModeFull, nil
+	case outputModeFull, outputModeSummary, outputModeMinimal:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid 'outputMode' %q: expected full, summary or minimal", mode)
+	}
+}
+
+// Output formats accepted by the 'outputFormat' key.
+const (
+	outputFormatText       = "text"
+	outputFormatArgoMetric = "argoMetric"
+)
+
+// Values accepted by 'metricValue', reported with outputFormatArgoMetric.
+const (
+	metricValueDuration = "durationMs"
+	metricValueStatus   = "status"
+)
+
+// argoMetric reports the result as a single number, so AnalysisTemplate
+// conditions can compare it without parsing the message.
+type argoMetric struct {
+	value string
+}
+
+// parseArgoMetric reads 'outputFormat' and 'metricValue'. It returns nil
+// for the default text format.
+func parseArgoMetric(config map[string]string) (*argoMetric, error) {
+	switch format := config["outputFormat"]; format {
+	case "", outputFormatText:
+		if _, ok := config["metricValue"]; ok {
+			return nil, fmt.Errorf("'metricValue' requires 'outputFormat' %s", outputFormatArgoMetric)
+		}
+		return nil, nil
+	case outputFormatArgoMetric:
+	default:
+		return nil, fmt.Errorf("invalid 'outputFormat' %q: expected %s or %s", format, outputFormatText, outputFormatArgoMetric)
+	}
+	// The message is the value itself, so nothing else may shape it
+	if key := firstKey(config, []string{"outputMode", "outputFooter", "requests", "matrix", "compareUri", "grpcHealth"}); key != "" {
+		return nil, fmt.Errorf("'outputFormat' %s cannot be combined with '%s'", outputFormatArgoMetric, key)
+	}
+	m := &argoMetric{value: metricValueDuration}
+	switch v := config["metricValue"]; v {
+	case "":
+	case metricValueDuration, metricValueStatus:
+		m.value = v
+	default:
+		return nil, fmt.Errorf("invalid 'metricValue' %q: expected %s or %s", v, metricValueDuration, metricValueStatus)
+	}
+	return m, nil
+}
+
+// apply replaces the message of result with the metric value. status is 0
+// when no response was received.
+func (m *argoMetric) apply(result *PluginOutput, status int, duration time.Duration) {
+	value := milliseconds(duration)
+	if m.value == metricValueStatus {
+		value = float64(status)
+	}
+	result.Value = &value
+	result.Message = strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatResponseMessage renders the Message for a completed request. Full
+// output embeds the body, summary output only the status and duration, and
+// minimal output leaves the Message empty so only Success is reported.
+func formatResponseMessage(mode string, resp *http.Response, body []byte, duration time.Duration) string {
+	switch mode {
+	case outputModeMinimal:
+		return ""
+	case outputModeSummary:
+		return fmt.Sprintf("Status: %s\nDuration: %s", resp.Status, duration.Round(time.Millisecond))
+	default:
+		if resp.Request != nil && resp.Request.Method == http.MethodHead {
+			return fmt.Sprintf("Status: %s\nContent-Length: %d", resp.Status, resp.ContentLength)
+		}
+		return fmt.Sprintf("Status: %s\nBody: %s", resp.Status, string(body))
+	}
+}
+
+// resultFooter is the 'outputFooter' line, a stable summary for log
+// scrapers. status is 0 when no response was received.
+func resultFooter(status int, duration time.Duration, success bool) string {
+	return fmt.Sprintf("CURL_RESULT status=%d duration_ms=%d success=%t", status, duration.Milliseconds(), success)
+}
+
+// defaultMaxMessageBytes keeps the message readable in the Argo Rollouts UI
+// when 'maxMessageBytes' is not set.
+const defaultMaxMessageBytes = 4096
+
+// truncateMessage cuts message to at most max bytes, not counting the
+// suffix that reports how much was dropped. It never splits a UTF-8
+// sequence. A max of 0 leaves the message whole.
+func truncateMessage(message string, max int) string {
+	if max <= 0 || len(message) <= max {
+		return message
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", message[:cut], len(message)-cut)
+}
+
+// describeTrailers flattens the response trailers, joining repeated
+// values. Trailers announced but never sent are left out.
+func describeTrailers(trailer http.Header) map[string]string {
+	trailers := make(map[string]string, len(trailer))
+	for name, values := range trailer {
+		if len(values) > 0 {
+			trailers[name] = strings.Join(values, ", ")
+		}
+	}
+	return trailers
+}
+
+// formatTrailers renders trailers as "Name: value" pairs in name order.
+func formatTrailers(trailers map[string]string) string {
+	names := make([]string, 0, len(trailers))
+	for name := range trailers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + ": " + trailers[name]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// appendLine adds line to message on a line of its own.
+func appendLine(message, line string) string {
+	if message == "" {
+		return line
+	}
+	return message + "\n" + line
+}
+
+// errorOutput builds the failed output for a request that got no response.
+func errorOutput(mode string, err error) PluginOutput {
+	output := PluginOutput{Success: false, FailureReason: err.Error()}
+	if mode != outputModeMinimal {
+		output.Message = fmt.Sprintf("Request error: %v", err)
+	}
+	return output
+}
+
+// wasCompressed reports whether the response body was content-encoded on
+// the wire. The transport strips Content-Encoding when it decompresses gzip
+// itself, which it records in resp.Uncompressed.
+func wasCompressed(resp *http.Response) bool {
+	if resp.Uncompressed {
+		return true
+	}
+	encoding := resp.Header.Get("Content-Encoding")
+	return encoding != "" && !strings.EqualFold(encoding, "identity")
+}
+
+// prettyJSON re-indents a JSON body for display. Bodies that are not
+// declared as JSON, or do not parse, are returned unchanged.
+func prettyJSON(contentType string, body []byte) []byte {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return body
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+// capturedBody returns the body to display when its Content-Type matches
+// one of the 'captureBodyForContentTypes' patterns, such as "text/*" or
+// "application/json", and only its size otherwise, to keep binary data
+// out of the rollout output. A response without a Content-Type never
+// matches.
+func capturedBody(patterns []string, contentType string, body []byte) []byte {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		for _, pattern := range patterns {
+			pattern = strings.ToLower(pattern)
+			if pattern == "*/*" || pattern == mediaType ||
+				strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+				return body
+			}
+		}
+	}
+	if contentType == "" {
+		contentType = "no content type"
+	}
+	return []byte(fmt.Sprintf("<%d bytes of %s, not captured>", len(body), contentType))
+}
+
+// RequestEcho describes the request that was sent, for 'echoRequest'.
+type RequestEcho struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// BodyLength is the request body size, or -1 when it was unknown.
+	BodyLength int64 `json:"bodyLength"`
+}
+
+// alwaysRedactedHeaders carry credentials whatever 'secretHeaders' says.
+var alwaysRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// echoRequest describes req with the values of credential headers and of
+// the headers listed in secretHeaders replaced, and URL passwords masked.
+func echoRequest(req *http.Request, secretHeaders []string) *RequestEcho {
+	secret := make(map[string]bool)
+	for _, names := range [][]string{alwaysRedactedHeaders, secretHeaders} {
+		for _, name := range names {
+			secret[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+
+	echo := &RequestEcho{Method: req.Method, URL: req.URL.Redacted(), BodyLength: req.ContentLength}
+	if req.Body == nil || req.Body == http.NoBody {
+		echo.BodyLength = 0
+	}
+	if len(req.Header) > 0 {
+		echo.Headers = make(map[string]string, len(req.Header))
+		for name, values := range req.Header {
+			value := strings.Join(values, ", ")
+			if secret[http.CanonicalHeaderKey(name)] {
+				value = "[REDACTED]"
+			}
+			echo.Headers[name] = value
+		}
+	}
+	return echo
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestOutputMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello from the canary"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		mode string
+		want *regexp.Regexp
+	}{
+		{mode: "", want: regexp.MustCompile(`^Status: 200 OK\nBody: hello from the canary$`)},
+		{mode: "full", want: regexp.MustCompile(`^Status: 200 OK\nBody: hello from the canary$`)},
+		{mode: "summary", want: regexp.MustCompile(`^Status: 200 OK\nDuration: [0-9.]+(ms|s)$`)},
+		{mode: "minimal", want: regexp.MustCompile(`^$`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "outputMode": tt.mode})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !output.Success {
+				t.Errorf("Expected successful response")
+			}
+			if !tt.want.MatchString(output.Message) {
+				t.Errorf("Message = %q, want match for %s", output.Message, tt.want)
+			}
+		})
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "outputMode": "verbose"}); err == nil {
+		t.Error("Expected error for unknown outputMode")
+	}
+}
+
+func TestContentTypeAndCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if r.URL.Path == "/plain" {
+			w.Write([]byte("plain body"))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("compressed body"))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL + "/gzip", "method": "GET"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.ContentType != "text/plain; charset=utf-8" || !output.Compressed {
+		t.Errorf("ContentType = %q, Compressed = %v; want text/plain and true", output.ContentType, output.Compressed)
+	}
+	if !strings.Contains(output.Message, "compressed body") {
+		t.Errorf("Expected decompressed body in message, got %q", output.Message)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/plain", "method": "GET"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Compressed {
+		t.Error("Expected uncompressed response to report Compressed = false")
+	}
+}
+
+func TestPartialBodyRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Failed to hijack connection: %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\npartial...")
+		buf.Flush()
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success {
+		t.Error("Expected truncated body to fail the probe")
+	}
+	if !strings.Contains(output.FailureReason, "body read failed after 10 bytes: unexpected EOF") {
+		t.Errorf("Unexpected FailureReason %q", output.FailureReason)
+	}
+	if !strings.Contains(output.Message, "Body: partial...") {
+		t.Errorf("Expected partial body in message, got %q", output.Message)
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"status":"ok","checks":[1,2]}`))
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.Write([]byte(`{"title":"bad"}`))
+		case "/invalid":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"status":"ok"}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		pretty string
+		want   string
+	}{
+		{path: "/json", pretty: "true", want: "Body: {\n  \"status\": \"ok\",\n  \"checks\": [\n    1,\n    2\n  ]\n}"},
+		{path: "/json", pretty: "false", want: `Body: {"status":"ok","checks":[1,2]}`},
+		{path: "/problem", pretty: "true", want: "Body: {\n  \"title\": \"bad\"\n}"},
+		{path: "/invalid", pretty: "true", want: `Body: {"status":`},
+		{path: "/text", pretty: "true", want: `Body: {"status":"ok"}`},
+	}
+	for _, tt := range tests {
+		output, err := runPlugin(t, map[string]string{"uri": server.URL + tt.path, "method": "GET", "prettyJson": tt.pretty})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasSuffix(output.Message, tt.want) {
+			t.Errorf("%s (prettyJson=%s): Message = %q, want suffix %q", tt.path, tt.pretty, output.Message, tt.want)
+		}
+	}
+}
+
+func TestEchoRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":           server.URL,
+		"method":        "POST",
+		"body":          "payload",
+		"headers":       `{"Authorization": "Bearer secret", "X-Api-Key": "k3y", "X-Team": "canary"}`,
+		"secretHeaders": "x-api-key",
+		"echoRequest":   "true",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	echo := output.RequestEcho
+	if echo == nil {
+		t.Fatal("Expected a request echo")
+	}
+	if echo.Method != "POST" || echo.URL != server.URL || echo.BodyLength != 7 {
+		t.Errorf("echo = %s %s (%d bytes), want POST %s (7 bytes)", echo.Method, echo.URL, echo.BodyLength, server.URL)
+	}
+	want := map[string]string{"Authorization": "[REDACTED]", "X-Api-Key": "[REDACTED]", "X-Team": "canary"}
+	for name, value := range want {
+		if echo.Headers[name] != value {
+			t.Errorf("echoed %s = %q, want %q", name, echo.Headers[name], value)
+		}
+	}
+
+	// The final request after redirects is echoed, and URL passwords are masked
+	u := strings.Replace(server.URL, "http://", "http://probe:hunter2@", 1)
+	output, err = runPlugin(t, map[string]string{"uri": u + "/old", "method": "GET", "echoRequest": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if echo := output.RequestEcho; echo == nil || echo.URL != strings.Replace(u, "hunter2", "xxxxx", 1)+"/new" || echo.BodyLength != 0 {
+		t.Errorf("Expected the redirected request with a masked password, got %+v", echo)
+	}
+
+	// A request that got no response is echoed too
+	output, err = runPlugin(t, map[string]string{"uri": "http://127.0.0.1:1/", "method": "GET", "echoRequest": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.RequestEcho == nil || output.RequestEcho.URL != "http://127.0.0.1:1/" {
+		t.Errorf("Expected the failed request to be echoed, got %+v", output.RequestEcho)
+	}
+
+	output, _ = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"})
+	if output.RequestEcho != nil {
+		t.Errorf("Expected no echo by default, got %+v", output.RequestEcho)
+	}
+}
+
+func TestCaptureBodyForContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"ok":true}`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<p>ok</p>`))
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/json", want: `Body: {"ok":true}`},
+		{path: "/text", want: `Body: <p>ok</p>`},
+		{path: "/image", want: `Body: <8 bytes of image/png, not captured>`},
+	}
+	for _, tt := range tests {
+		output, err := runPlugin(t, map[string]string{
+			"uri":                        server.URL + tt.path,
+			"method":                     "GET",
+			"captureBodyForContentTypes": "text/*, application/json",
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !output.Success || !strings.HasSuffix(output.Message, tt.want) {
+			t.Errorf("%s: Message = %q, want suffix %q", tt.path, output.Message, tt.want)
+		}
+	}
+}
+
+func TestOutputFooter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("line one\nline two"))
+	}))
+	defer server.Close()
+
+	footer := regexp.MustCompile(`\nCURL_RESULT status=202 duration_ms=\d+ success=true$`)
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "outputFooter": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !footer.MatchString(output.Message) {
+		t.Errorf("Message = %q, want it to end with the footer", output.Message)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": "http://127.0.0.1:1", "method": "GET", "outputFooter": "true", "outputMode": "minimal"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !regexp.MustCompile(`^CURL_RESULT status=0 duration_ms=\d+ success=false$`).MatchString(output.Message) {
+		t.Errorf("Message = %q, want only the footer", output.Message)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output.Message, "CURL_RESULT") {
+		t.Errorf("Message = %q, want no footer by default", output.Message)
+	}
+}
+
+func TestArgoMetricFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL + "/missing", "method": "GET", "outputFormat": "argoMetric", "metricValue": "status"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || output.Value == nil || *output.Value != 404 || output.Message != "404" {
+		t.Errorf("Expected the status as the value, got %+v", output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "outputFormat": "argoMetric"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.Value == nil || *output.Value <= 0 || !regexp.MustCompile(`^[\d.]+$`).MatchString(output.Message) {
+		t.Errorf("Expected the duration as the value, got %+v", output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": "http://127.0.0.1:1", "method": "GET", "outputFormat": "argoMetric", "metricValue": "status"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || output.Value == nil || *output.Value != 0 || output.FailureReason == "" {
+		t.Errorf("Expected a 0 status without a response, got %+v", output)
+	}
+
+	// Wrappers report one value for the Run, not one per attempt
+	for _, config := range []map[string]string{
+		{"uri": "http://127.0.0.1:1", "fallbackUri": server.URL + "/missing"},
+		{"uri": server.URL + "/missing", "requiredConsecutiveSuccesses": "2", "consecutiveMaxAttempts": "2", "consecutiveInterval": "1ms"},
+	} {
+		config["method"], config["outputFormat"], config["metricValue"] = "GET", "argoMetric", "status"
+		output, err = runPlugin(t, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if output.Success || output.Value == nil || *output.Value != 404 || output.Message != "404" {
+			t.Errorf("Expected the status as the value for %v, got %+v", config, output)
+		}
+	}
+
+	for _, config := range []map[string]string{
+		{"outputFormat": "json"},
+		{"metricValue": "status"},
+		{"outputFormat": "argoMetric", "metricValue": "body"},
+		{"outputFormat": "argoMetric", "outputMode": "summary"},
+		{"outputFormat": "argoMetric", "outputFooter": "true"},
+		{"outputFormat": "argoMetric", "requests": `[{}]`},
+		{"outputFormat": "argoMetric", "grpcHealth": "true"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		max     int
+		want    string
+	}{
+		{message: "short", max: 10, want: "short"},
+		{message: "exactly10!", max: 10, want: "exactly10!"},
+		{message: "0123456789abc", max: 10, want: "0123456789...(truncated 3 bytes)"},
+		{message: "ab€cd", max: 3, want: "ab...(truncated 5 bytes)"},
+		{message: "unlimited", max: 0, want: "unlimited"},
+	}
+	for _, tt := range tests {
+		if got := truncateMessage(tt.message, tt.max); got != tt.want {
+			t.Errorf("truncateMessage(%q, %d) = %q, want %q", tt.message, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestMaxMessageBytes(t *testing.T) {
+	body := strings.Repeat("x", 10000) + "needle"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	// The default limit applies, yet the match sees the whole body
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "bodyMatch": "needle$", "outputFooter": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected the match to see the whole body, got %q", output.Message[:100])
+	}
+	if !strings.Contains(output.Message, "...(truncated ") || !strings.Contains(output.Message, "\nCURL_RESULT status=200") {
+		t.Errorf("Expected a truncated message ending with the footer, got %d bytes", len(output.Message))
+	}
+	if n := strings.Index(output.Message, "..."); n != defaultMaxMessageBytes {
+		t.Errorf("Message cut at %d bytes, want %d", n, defaultMaxMessageBytes)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "maxMessageBytes": "0"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(output.Message, "needle") {
+		t.Errorf("Expected the whole body with maxMessageBytes 0, got %d bytes", len(output.Message))
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "maxMessageBytes": "-1"}); err == nil {
+		t.Error("Expected error for negative maxMessageBytes")
+	}
+
+	// Wrappers combine the entries' messages and are cut once
+	for _, config := range []map[string]string{
+		{"requests": fmt.Sprintf(`[{"uri": %[1]q}, {"uri": %[1]q}, {"uri": %[1]q}, {"uri": %[1]q}, {"uri": %[1]q}]`, server.URL)},
+		{"uri": server.URL, "compareUri": server.URL},
+	} {
+		config["method"], config["maxMessageBytes"] = "GET", "1000"
+		output, err := runPlugin(t, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n := strings.Index(output.Message, "...(truncated "); n < 0 || n > 1000 {
+			t.Errorf("Expected the message for %v cut at 1000 bytes, got %d bytes", config, len(output.Message))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ---- Pagination ----
+
+// defaultMaxPages bounds 'followPagination' when 'maxPages' is unset.
+const defaultMaxPages = 100
+
+// paginationKeys are the settings that only apply with 'followPagination'.
+var paginationKeys = []string{"paginationNextPath", "paginationItemsPath", "maxPages", "minTotalItems"}
+
+// pagination holds the 'followPagination' settings. The next page is read
+// from the JSON field at nextPath, or from the Link header when it is
+// empty; the items of a page are the array at itemsPath, or the whole body
+// when it is empty. minItems is negative when 'minTotalItems' is unset.
+type pagination struct {
+	nextPath  string
+	itemsPath string
+	maxPages  int
+	minItems  int
+	// private are the headers not sent to a next page on another host
+	private []string
+}
+
+func parsePagination(config map[string]string) (*pagination, error) {
+	enabled, err := configBool(config, "followPagination", false)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		if key := firstKey(config, paginationKeys); key != "" {
+			return nil, fmt.Errorf("'%s' requires 'followPagination'", key)
+		}
+		return nil, nil
+	}
+
+	p := &pagination{nextPath: config["paginationNextPath"], itemsPath: config["paginationItemsPath"], private: privateHeaders(config)}
+	if p.maxPages, err = configInt(config, "maxPages", defaultMaxPages); err != nil {
+		return nil, err
+	}
+	if p.maxPages < 1 {
+		return nil, fmt.Errorf("invalid 'maxPages' in config: must be at least 1")
+	}
+	if p.minItems, err = configInt(config, "minTotalItems", -1); err != nil {
+		return nil, err
+	}
+	if _, ok := config["minTotalItems"]; ok && p.minItems < 0 {
+		return nil, fmt.Errorf("invalid 'minTotalItems' in config: must not be negative")
+	}
+	return p, nil
+}
+
+// privateHeaders lists the headers of the probe that carry credentials or
+// are meant for the probed host only: authorization and cookies, the
+// custom 'headers' and the idempotency key.
+func privateHeaders(config map[string]string) []string {
+	private := []string{"Authorization", "Proxy-Authorization", "Cookie"}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(config["headers"]), &headers); err == nil {
+		for name := range headers {
+			private = append(private, name)
+		}
+	}
+	if config["idempotencyKey"] != "" {
+		header := config["idempotencyKeyHeader"]
+		if header == "" {
+			header = "Idempotency-Key"
+		}
+		private = append(private, header)
+	}
+	return private
+}
+
+// pageResult is the outcome of following the pages of a list endpoint.
+// err records why following stopped early.
+type pageResult struct {
+	pages int
+	items int
+	err   error
+}
+
+// follow counts the items of the first response, then fetches each next
+// page with a GET carrying the headers of req, until a page has no next
+// link or maxPages is reached. The first response must already be read.
+func (p *pagination) follow(ctx context.Context, client *http.Client, policy retryPolicy, req *http.Request, resp *http.Response, body []byte) pageResult {
+	var result pageResult
+	for {
+		result.pages++
+		n, next, err := p.readPage(resp, body)
+		if err != nil {
+			result.err = fmt.Errorf("page %d: %w", result.pages, err)
+			return result
+		}
+		result.items += n
+		if next == nil {
+			return result
+		}
+		if result.pages == p.maxPages {
+			result.err = fmt.Errorf("more than 'maxPages' %d pages", p.maxPages)
+			return result
+		}
+
+		pageReq := req.Clone(ctx)
+		pageReq.Method = http.MethodGet
+		pageReq.Body, pageReq.GetBody, pageReq.ContentLength = nil, nil, 0
+		pageReq.Header.Del("Content-Type")
+		pageReq.URL = next
+		if next.Host != req.URL.Host {
+			// 'hostHeader' and credentials only apply to the probed host,
+			// as net/http does for redirects to another host
+			pageReq.Host = next.Host
+			for _, name := range p.private {
+				pageReq.Header.Del(name)
+			}
+		}
+
+		resp, err = doWithRetry(ctx, client, policy, pageReq)
+		if err != nil {
+			result.err = fmt.Errorf("page %d: %w", result.pages+1, err)
+			return result
+		}
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			result.err = fmt.Errorf("page %d: body read failed: %w", result.pages+1, err)
+			return result
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			result.err = fmt.Errorf("page %d: status %s", result.pages+1, resp.Status)
+			return result
+		}
+	}
+}
+
+// readPage returns the number of items on a page and the absolute URL of
+// the next one, or nil on the last page.
+func (p *pagination) readPage(resp *http.Response, body []byte) (int, *url.URL, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return 0, nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	items, err := evalJSONPath(doc, p.itemsPath)
+	if err != nil {
+		return 0, nil, fmt.Errorf("items: %w", err)
+	}
+	list, ok := items.([]interface{})
+	if !ok {
+		return 0, nil, fmt.Errorf("items: expected an array, got %s", jsonKind(items))
+	}
+
+	var raw string
+	if p.nextPath == "" {
+		raw = nextLink(resp.Header.Values("Link"))
+	} else if v, err := evalJSONPath(doc, p.nextPath); err == nil && v != nil {
+		// A missing or null field marks the last page
+		if raw, ok = v.(string); !ok {
+			return 0, nil, fmt.Errorf("next page: expected a string, got %s", jsonKind(v))
+		}
+	}
+	if raw == "" {
+		return len(list), nil, nil
+	}
+	next, err := resp.Request.URL.Parse(raw)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid next page URL %q: %w", raw, err)
+	}
+	return len(list), next, nil
+}
+
+// nextLink returns the target of the rel="next" entry of RFC 8288 Link
+// header values, or "" when there is none.
+func nextLink(values []string) string {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			target, params, _ := strings.Cut(strings.TrimSpace(link), ";")
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// criterion reports whether every page was read and, with
+// 'minTotalItems', whether enough items were found across them.
+func (p *pagination) criterion(r pageResult) criterion {
+	detail := fmt.Sprintf("%d items across %d pages", r.items, r.pages)
+	if r.err != nil {
+		return criterion{name: "pagination", detail: fmt.Sprintf("%s before failing: %v", detail, r.err)}
+	}
+	if p.minItems >= 0 {
+		return criterion{
+			name:   "pagination",
+			passed: r.items >= p.minItems,
+			detail: fmt.Sprintf("%s (minimum %d)", detail, p.minItems),
+		}
+	}
+	return criterion{name: "pagination", passed: true, detail: detail}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{values: []string{`<https://api.example.com/items?page=2>; rel="next"`}, want: "https://api.example.com/items?page=2"},
+		{values: []string{`</items?page=1>; rel="prev", </items?page=3>; rel="next"`}, want: "/items?page=3"},
+		{values: []string{`</first>; rel="first"`, `</n>; rel="next last"`}, want: "/n"},
+		{values: []string{`</items?page=9>; rel=NEXT`}, want: "/items?page=9"},
+		{values: []string{`</items?page=1>; rel="prev"`}, want: ""},
+		{values: nil, want: ""},
+	}
+	for _, tt := range tests {
+		if got := nextLink(tt.values); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	// Three pages of two items, linked by the Link header under /link and
+	// by a "next" field under /json
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		next := ""
+		if page < 3 {
+			next = fmt.Sprintf("%s?page=%d", r.URL.Path, page+1)
+		}
+		switch r.URL.Path {
+		case "/link":
+			if next != "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next))
+			}
+			fmt.Fprint(w, `[1, 2]`)
+		case "/json":
+			fmt.Fprintf(w, `{"data": {"items": [1, 2]}, "next": %q}`, next)
+		case "/broken":
+			if page == 2 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next))
+			fmt.Fprint(w, `[1, 2]`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		config      map[string]string
+		wantSuccess bool
+		wantPages   int
+		wantItems   int
+		wantDetail  string
+	}{
+		{
+			name:        "link header",
+			config:      map[string]string{"uri": server.URL + "/link"},
+			wantSuccess: true, wantPages: 3, wantItems: 6,
+		},
+		{
+			name:        "json field",
+			config:      map[string]string{"uri": server.URL + "/json", "paginationNextPath": "next", "paginationItemsPath": "data.items"},
+			wantSuccess: true, wantPages: 3, wantItems: 6,
+		},
+		{
+			name:        "enough items",
+			config:      map[string]string{"uri": server.URL + "/link", "minTotalItems": "6"},
+			wantSuccess: true, wantPages: 3, wantItems: 6,
+		},
+		{
+			name:        "too few items",
+			config:      map[string]string{"uri": server.URL + "/link", "minTotalItems": "7"},
+			wantSuccess: false, wantPages: 3, wantItems: 6, wantDetail: "(minimum 7)",
+		},
+		{
+			name:        "page limit",
+			config:      map[string]string{"uri": server.URL + "/link", "maxPages": "2"},
+			wantSuccess: false, wantPages: 2, wantItems: 4, wantDetail: "more than 'maxPages' 2 pages",
+		},
+		{
+			name:        "failing page",
+			config:      map[string]string{"uri": server.URL + "/broken"},
+			wantSuccess: false, wantPages: 1, wantItems: 2, wantDetail: "page 2: status 500",
+		},
+		{
+			name:        "items not an array",
+			config:      map[string]string{"uri": server.URL + "/json"},
+			wantSuccess: false, wantPages: 1, wantDetail: "expected an array, got an object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["method"] = "GET"
+			tt.config["headers"] = `{"X-Token": "secret"}`
+			tt.config["followPagination"] = "true"
+			output, err := runPlugin(t, tt.config)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if output.Success != tt.wantSuccess || output.Pages != tt.wantPages || output.TotalItems != tt.wantItems {
+				t.Errorf("got success=%v pages=%d items=%d, want %v, %d, %d\n%s",
+					output.Success, output.Pages, output.TotalItems, tt.wantSuccess, tt.wantPages, tt.wantItems, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantDetail) {
+				t.Errorf("message %q does not contain %q", output.Message, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestPaginationCrossHost(t *testing.T) {
+	var leaked http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Clone()
+		fmt.Fprint(w, `[3]`)
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			w.Header().Set("Link", `</other>; rel="next"`)
+		case "/other":
+			if r.Header.Get("Authorization") == "" || r.Header.Get("X-Api-Key") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		default:
+			w.Header().Set("Link", fmt.Sprintf(`<%s/list>; rel="next"`, other.URL))
+		}
+		fmt.Fprint(w, `[1, 2]`)
+	}))
+	defer server.Close()
+
+	config := func(path string) map[string]string {
+		return map[string]string{
+			"uri": server.URL + path, "method": "GET", "followPagination": "true",
+			"headers":        `{"Authorization": "Bearer sa-token", "X-Api-Key": "k", "Cookie": "session=1", "Accept": "application/json"}`,
+			"idempotencyKey": "auto",
+		}
+	}
+
+	output, err := runPlugin(t, config("/"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.TotalItems != 3 {
+		t.Fatalf("Expected both pages to be read, got %+v", output)
+	}
+	for _, name := range []string{"Authorization", "X-Api-Key", "Cookie", "Idempotency-Key", "Accept"} {
+		if v := leaked.Get(name); v != "" {
+			t.Errorf("Page on another host received %s: %q", name, v)
+		}
+	}
+
+	// Pages on the probed host keep the probe's headers
+	output, err = runPlugin(t, config("/same"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.TotalItems != 4 {
+		t.Errorf("Expected the same-host page to be authorized, got %+v", output)
+	}
+}
+
+func TestPaginationConfig(t *testing.T) {
+	tests := []map[string]string{
+		{"maxPages": "5"},
+		{"followPagination": "true", "maxPages": "0"},
+		{"followPagination": "true", "minTotalItems": "-1"},
+		{"followPagination": "true", "sse": "true"},
+		{"followPagination": "true", "method": "HEAD"},
+	}
+	for _, config := range tests {
+		config["uri"] = "http://127.0.0.1:1"
+		if config["method"] == "" {
+			config["method"] = "GET"
+		}
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("expected a config error for %v", config)
+		}
+	}
+}
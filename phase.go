@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ---- Result Phase ----
+
+// Phases reported in PluginOutput.Phase.
+const (
+	phaseSuccessful   = "Successful"
+	phaseFailed       = "Failed"
+	phaseInconclusive = "Inconclusive"
+	phaseError        = "Error"
+)
+
+var phases = []string{phaseSuccessful, phaseFailed, phaseInconclusive, phaseError}
+
+// phaseMapping is the phase reported for a passing and for a failing
+// probe, from 'onSuccess' and 'onFailure'.
+type phaseMapping struct {
+	onSuccess, onFailure string
+}
+
+func parsePhaseMapping(config map[string]string) (phaseMapping, error) {
+	m := phaseMapping{onSuccess: phaseSuccessful, onFailure: phaseFailed}
+	for key, target := range map[string]*string{"onSuccess": &m.onSuccess, "onFailure": &m.onFailure} {
+		v, ok := config[key]
+		if !ok {
+			continue
+		}
+		if !slices.Contains(phases, v) {
+			return phaseMapping{}, fmt.Errorf("invalid '%s' %q: expected one of %v", key, v, phases)
+		}
+		*target = v
+	}
+	return m, nil
+}
+
+// phase returns the phase for the outcome of a probe.
+func (m phaseMapping) phase(success bool) string {
+	if success {
+		return m.onSuccess
+	}
+	return m.onFailure
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPhase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		config    map[string]string
+		wantPhase string
+	}{
+		{name: "default success", config: map[string]string{"uri": server.URL}, wantPhase: "Successful"},
+		{name: "default failure", config: map[string]string{"uri": server.URL + "/error"}, wantPhase: "Failed"},
+		{name: "failure as inconclusive", config: map[string]string{"uri": server.URL + "/error", "onFailure": "Inconclusive"}, wantPhase: "Inconclusive"},
+		{name: "success mapped", config: map[string]string{"uri": server.URL, "onSuccess": "Inconclusive"}, wantPhase: "Inconclusive"},
+		{name: "unreachable", config: map[string]string{"uri": "http://127.0.0.1:1", "onFailure": "Error"}, wantPhase: "Error"},
+		{name: "batch", config: map[string]string{"requests": `[{"uri": "` + server.URL + `"}, {"uri": "` + server.URL + `/error"}]`, "onFailure": "Inconclusive"}, wantPhase: "Inconclusive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["method"] = "GET"
+			output, err := runPlugin(t, tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Phase != tt.wantPhase {
+				t.Errorf("Phase = %q, want %q (%+v)", output.Phase, tt.wantPhase, output)
+			}
+		})
+	}
+
+	for _, config := range []map[string]string{
+		{"onFailure": "Inconclusve"},
+		{"onSuccess": "successful"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
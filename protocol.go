@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ---- Protocol Version ----
+
+// protocolVersions lists the values accepted by 'protocolVersion'.
+var protocolVersions = []string{"1.0", "1.1"}
+
+// parseProtocolVersion reads 'protocolVersion', which pins the request to
+// HTTP/1.0 or HTTP/1.1. It returns "" when unset.
+func parseProtocolVersion(config map[string]string) (string, error) {
+	version, ok := config["protocolVersion"]
+	if !ok {
+		return "", nil
+	}
+	if !slices.Contains(protocolVersions, version) {
+		return "", fmt.Errorf("invalid 'protocolVersion' %q: expected 1.0 or 1.1", version)
+	}
+	if key := firstKey(config, []string{"proxyUrl", "proxyRules"}); version == "1.0" && key != "" {
+		return "", fmt.Errorf("'protocolVersion' 1.0 cannot be combined with '%s'", key)
+	}
+	return version, nil
+}
+
+// http10Transport sends requests as HTTP/1.0, which http.Transport cannot:
+// it always writes HTTP/1.1. Each request gets its own connection, opened
+// with the transport's dialer and TLS settings, and the body is sent with a
+// Content-Length, as HTTP/1.0 has no keep-alive or chunked encoding. The
+// transport's proxy, including one from HTTP_PROXY or HTTPS_PROXY, is not
+// used.
+type http10Transport struct {
+	transport *http.Transport
+}
+
+func (t *http10Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	// http.Transport checks these before writing them; so must we, or a
+	// CR or LF in a configured value would inject headers of its own
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	if !httpguts.ValidHostHeader(host) {
+		return nil, fmt.Errorf("invalid Host header %q", host)
+	}
+	for name, values := range req.Header {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid header field name %q", name)
+		}
+		for _, value := range values {
+			if !httpguts.ValidHeaderFieldValue(value) {
+				return nil, fmt.Errorf("invalid header field value for %q", name)
+			}
+		}
+	}
+
+	ctx := req.Context()
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	conn, err := t.transport.DialContext(ctx, "tcp", net.JoinHostPort(req.URL.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme == "https" {
+		tlsConfig := t.transport.TLSClientConfig.Clone()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = req.URL.Hostname()
+		}
+		tlsConfig.NextProtos = nil
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	// Reads and writes have no context of their own, so bound them by ctx
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	fail := func(err error) (*http.Response, error) {
+		stop()
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "%s %s HTTP/1.0\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), host)
+	if len(body) > 0 || req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch {
+		fmt.Fprintf(&head, "Content-Length: %d\r\n", len(body))
+	}
+	if err := req.Header.WriteSubset(&head, map[string]bool{"Host": true, "Content-Length": true, "Transfer-Encoding": true, "Connection": true}); err != nil {
+		return fail(err)
+	}
+	head.WriteString("\r\n")
+	head.Write(body)
+	if _, err := conn.Write(head.Bytes()); err != nil {
+		return fail(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fail(err)
+	}
+	resp.Body = &http10Body{ReadCloser: resp.Body, conn: conn, stop: stop}
+	return resp, nil
+}
+
+// http10Body closes the connection along with the response body.
+type http10Body struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+func (b *http10Body) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	b.conn.Close()
+	return err
+}
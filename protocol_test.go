@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProtocolVersion(t *testing.T) {
+	var proto, body string
+	var contentLength int64
+	var chunked bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+		contentLength = r.ContentLength
+		chunked = len(r.TransferEncoding) > 0
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "POST", "body": `{"ping": true}`, "protocolVersion": "1.0", "bodyMatch": "ok"})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected success, got %v %+v", err, output)
+	}
+	if proto != "HTTP/1.0" || contentLength != 14 || chunked || body != `{"ping": true}` {
+		t.Errorf("Server received %s with Content-Length %d (chunked %v) and body %q", proto, contentLength, chunked, body)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "protocolVersion": "1.1"})
+	if err != nil || !output.Success || proto != "HTTP/1.1" {
+		t.Errorf("Expected an HTTP/1.1 request, got %s: %v %+v", proto, err, output)
+	}
+
+	// Values that would split the request head are refused, not written
+	for _, config := range []map[string]string{
+		{"hostHeader": "canary.example.com\r\nX-Injected: 1"},
+		{"headers": `{"X-Test": "a\r\nX-Injected: 1"}`},
+	} {
+		proto = ""
+		config["uri"], config["method"], config["protocolVersion"] = server.URL, "GET", "1.0"
+		output, err := runPlugin(t, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if output.Success || proto != "" {
+			t.Errorf("Expected the request for %v to be refused, got %+v", config, output)
+		}
+	}
+
+	for _, config := range []map[string]string{
+		{"protocolVersion": "2"},
+		{"protocolVersion": "1"},
+		{"protocolVersion": "1.0", "proxyUrl": "http://proxy.example.com:3128"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+// A legacy server answers in HTTP/1.0, delimiting the body by closing the
+// connection.
+func TestProtocolVersionLegacyServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	requestLine := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		line, _ := reader.ReadString('\n')
+		requestLine <- strings.TrimSpace(line)
+		for {
+			if l, err := reader.ReadString('\n'); err != nil || l == "\r\n" {
+				break
+			}
+		}
+		io.WriteString(conn, "HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\n\r\nlegacy ok")
+	}()
+
+	output, err := runPlugin(t, map[string]string{"uri": "http://" + listener.Addr().String() + "/health?full=1", "method": "GET", "protocolVersion": "1.0", "requireProtocol": "HTTP/1.0", "bodyMatch": "legacy ok"})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected success, got %v %+v", err, output)
+	}
+	if line := <-requestLine; line != "GET /health?full=1 HTTP/1.0" {
+		t.Errorf("Request line = %q", line)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ---- Pushgateway ----
+
+const (
+	defaultPushgatewayJob = "argo-rollouts-plugin-curl"
+	pushTimeout           = 5 * time.Second
+)
+
+// pushgateway pushes the outcome of a Run to a Prometheus Pushgateway.
+type pushgateway struct {
+	url *url.URL
+	job string
+}
+
+// parsePushgateway reads 'pushgatewayUrl' and 'pushgatewayJob'. It returns
+// nil when pushing is not configured.
+func parsePushgateway(config map[string]string) (*pushgateway, error) {
+	raw := config["pushgatewayUrl"]
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'pushgatewayUrl' in config: %w", err)
+	}
+	job := config["pushgatewayJob"]
+	if job == "" {
+		job = defaultPushgatewayJob
+	}
+	return &pushgateway{url: u, job: job}, nil
+}
+
+// groupURL returns the endpoint of the job's metric group. Job names
+// containing a slash use the Pushgateway's base64 path encoding.
+func (g *pushgateway) groupURL() string {
+	segment := "job/" + url.PathEscape(g.job)
+	if strings.Contains(g.job, "/") {
+		segment = "job@base64/" + base64.RawURLEncoding.EncodeToString([]byte(g.job))
+	}
+	return strings.TrimSuffix(g.url.String(), "/") + "/metrics/" + segment
+}
+
+// push replaces the job's metrics with the outcome of a Run. A status of 0
+// means no response was received.
+func (g *pushgateway) push(ctx context.Context, success bool, status int, duration time.Duration) error {
+	var body bytes.Buffer
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(&body, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+	}
+	successValue := 0.0
+	if success {
+		successValue = 1
+	}
+	gauge("rollout_probe_success", "Whether the probe passed.", successValue)
+	gauge("rollout_probe_duration_seconds", "Duration of the probe request.", duration.Seconds())
+	gauge("rollout_probe_status_code", "HTTP status of the probe response, 0 if none was received.", float64(status))
+
+	// The push still goes out when the Run itself was cancelled or timed out
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, g.groupURL(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestPushgateway(t *testing.T) {
+	type push struct {
+		method, path, body string
+	}
+	pushes := make(chan push, 1)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushes <- push{method: r.Method, path: r.URL.EscapedPath(), body: string(body)}
+	}))
+	defer gateway.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		config   map[string]string
+		wantPath string
+		wantBody []string
+	}{
+		{
+			name:     "default job",
+			config:   map[string]string{"uri": server.URL},
+			wantPath: "/metrics/job/argo-rollouts-plugin-curl",
+			wantBody: []string{"rollout_probe_success 0\n", "rollout_probe_status_code 418\n", "# TYPE rollout_probe_duration_seconds gauge\n"},
+		},
+		{
+			name:     "job with a slash",
+			config:   map[string]string{"uri": server.URL, "pushgatewayJob": "canary/checkout"},
+			wantPath: "/metrics/job@base64/Y2FuYXJ5L2NoZWNrb3V0",
+		},
+		{
+			name:     "no response",
+			config:   map[string]string{"uri": "http://127.0.0.1:1", "pushgatewayJob": "canary"},
+			wantPath: "/metrics/job/canary",
+			wantBody: []string{"rollout_probe_success 0\n", "rollout_probe_status_code 0\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["method"] = "GET"
+			tt.config["pushgatewayUrl"] = gateway.URL + "/"
+			if _, err := runPlugin(t, tt.config); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := <-pushes
+			if got.method != http.MethodPut || got.path != tt.wantPath {
+				t.Errorf("push = %s %s, want PUT %s", got.method, got.path, tt.wantPath)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(got.body, want) {
+					t.Errorf("push body %q does not contain %q", got.body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPushgatewayWrapper(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		pushes = append(pushes, string(body))
+		mu.Unlock()
+	}))
+	defer gateway.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	// The last request passes, but the batch as a whole fails
+	requests := `[{"uri": "` + server.URL + `/fail"}, {"uri": "` + server.URL + `"}]`
+	output, err := runPlugin(t, map[string]string{"method": "GET", "requests": requests, "pushgatewayUrl": gateway.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success {
+		t.Fatalf("Expected the batch to fail, got %+v", output)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 1 || !strings.Contains(pushes[0], "rollout_probe_success 0\n") {
+		t.Errorf("Expected one push of the failed batch, got %q", pushes)
+	}
+}
+
+func TestPushgatewayFailureIsLogged(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer gateway.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	plugin := &HTTPPlugin{Logger: hclog.New(&hclog.LoggerOptions{Output: &logs})}
+	input, _ := json.Marshal(PluginInput{Config: map[string]string{"uri": server.URL, "method": "GET", "pushgatewayUrl": gateway.URL}})
+	raw, err := plugin.Run(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var output PluginOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		t.Fatalf("Failed to unmarshal output: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected push failure to be non-fatal, got: %v", output.Message)
+	}
+	if !strings.Contains(logs.String(), "failed to push result to pushgateway") {
+		t.Errorf("Expected warning in logs, got %q", logs.String())
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "pushgatewayUrl": "not a url"}); err == nil {
+		t.Error("Expected error for invalid pushgatewayUrl")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"golang.org/x/time/rate"
+)
+
+// ---- Global Rate Limiter ----
+
+// Environment variables configuring the process-wide rate limiter. The
+// limiter is disabled unless PLUGIN_RATE_LIMIT is set.
+const (
+	rateLimitEnv = "PLUGIN_RATE_LIMIT" // requests per second
+	rateBurstEnv = "PLUGIN_RATE_BURST" // bucket size, default 1
+)
+
+// rateLimiter is shared by every Run in the process; nil means unlimited.
+var rateLimiter *rate.Limiter
+
+// newRateLimiterFromEnv builds the limiter described by the environment,
+// returning nil when rate limiting is not configured.
+func newRateLimiterFromEnv() (*rate.Limiter, error) {
+	raw := os.Getenv(rateLimitEnv)
+	if raw == "" {
+		return nil, nil
+	}
+	limit, err := strconv.ParseFloat(raw, 64)
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("invalid %s %q: expected a positive number of requests per second", rateLimitEnv, raw)
+	}
+
+	burst := 1
+	if raw := os.Getenv(rateBurstEnv); raw != "" {
+		if burst, err = strconv.Atoi(raw); err != nil || burst < 1 {
+			return nil, fmt.Errorf("invalid %s %q: expected a positive integer", rateBurstEnv, raw)
+		}
+	}
+	return rate.NewLimiter(rate.Limit(limit), burst), nil
+}
+
+// rateLimitedTransport waits for a token before every outbound request,
+// including retries and redirects. Waiting honors the request context.
+type rateLimitedTransport struct {
+	limiter *rate.Limiter
+	next    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, fmt.Errorf("rate limiter: %w", err)
+	}
+	return t.next.RoundTrip(req)
+}
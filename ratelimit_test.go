@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimiterFromEnv(t *testing.T) {
+	t.Setenv(rateLimitEnv, "")
+	if limiter, err := newRateLimiterFromEnv(); err != nil || limiter != nil {
+		t.Errorf("Expected no limiter when unset, got %v, %v", limiter, err)
+	}
+
+	t.Setenv(rateLimitEnv, "2.5")
+	t.Setenv(rateBurstEnv, "3")
+	limiter, err := newRateLimiterFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if limiter.Limit() != 2.5 || limiter.Burst() != 3 {
+		t.Errorf("limiter = %v/%d, want 2.5/3", limiter.Limit(), limiter.Burst())
+	}
+
+	for _, env := range [][2]string{{"fast", "1"}, {"0", "1"}, {"1", "0"}} {
+		t.Setenv(rateLimitEnv, env[0])
+		t.Setenv(rateBurstEnv, env[1])
+		if _, err := newRateLimiterFromEnv(); err == nil {
+			t.Errorf("Expected error for %s=%q %s=%q", rateLimitEnv, env[0], rateBurstEnv, env[1])
+		}
+	}
+}
+
+func TestRateLimiterSpacesRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rateLimiter = rate.NewLimiter(rate.Every(50*time.Millisecond), 1)
+	defer func() { rateLimiter = nil }()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"})
+			if err != nil || !output.Success {
+				t.Errorf("Expected rate limited request to succeed: %v %v", err, output.Message)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The first request uses the initial token; the other three wait ~50ms each.
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("Requests finished in %v, expected the limiter to space them out", elapsed)
+	}
+}
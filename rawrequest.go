@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ---- Raw Requests ----
+
+// parseRawRequest parses raw as a complete HTTP/1.x request (request line,
+// headers and optional body) and rebinds it to target. Only the path and
+// query of the request line are kept; the connection always goes to the
+// configured uri, so a captured Host header does not redirect the probe.
+func parseRawRequest(ctx context.Context, raw string, target *url.URL) (*http.Request, error) {
+	// Captured requests are often pasted without the blank line that
+	// terminates the header block.
+	if !strings.Contains(raw, "\r\n\r\n") && !strings.Contains(raw, "\n\n") {
+		raw = strings.TrimRight(raw, "\r\n") + "\r\n\r\n"
+	}
+
+	parsed, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'rawRequest': %w", err)
+	}
+	body, err := io.ReadAll(parsed.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'rawRequest' body: %w", err)
+	}
+
+	u := *target
+	u.Path = parsed.URL.Path
+	u.RawPath = parsed.URL.RawPath
+	u.RawQuery = parsed.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(ctx, parsed.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range parsed.Header {
+		req.Header[name] = values
+	}
+	return req, nil
+}
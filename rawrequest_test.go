@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRawRequest(t *testing.T) {
+	var gotMethod, gotPath, gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotHeader, gotBody = r.Method, r.URL.RequestURI(), r.Header.Get("X-Probe"), string(body)
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri": server.URL,
+		"rawRequest": "PUT /items/1?force=true HTTP/1.1\r\n" +
+			"Host: production.example.com\r\n" +
+			"X-Probe: canary\r\n" +
+			"Content-Length: 5\r\n" +
+			"\r\n" +
+			"hello",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Fatalf("Expected successful response, got: %v", output.Message)
+	}
+	if gotMethod != "PUT" || gotPath != "/items/1?force=true" || gotHeader != "canary" || gotBody != "hello" {
+		t.Errorf("Unexpected request: %s %s X-Probe=%q body=%q", gotMethod, gotPath, gotHeader, gotBody)
+	}
+
+	// A header block without the terminating blank line is accepted
+	output, err = runPlugin(t, map[string]string{
+		"uri":        server.URL,
+		"rawRequest": "GET /ping HTTP/1.1\nHost: production.example.com",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || gotMethod != "GET" || gotPath != "/ping" {
+		t.Errorf("Unexpected request %s %s: %v", gotMethod, gotPath, output.Message)
+	}
+}
+
+func TestRawRequestInvalid(t *testing.T) {
+	for _, config := range []map[string]string{
+		{"uri": "http://127.0.0.1", "rawRequest": "not a request"},
+		{"uri": "http://127.0.0.1", "rawRequest": "GET / HTTP/1.1\r\n\r\n", "multipart": "[]"},
+	} {
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ---- Redirects ----
+
+// defaultMaxRedirects is the number of redirects followed when
+// 'maxRedirects' is not set.
+const defaultMaxRedirects = 10
+
+// errRedirectRejected reports a redirect refused by the redirect policy.
+type errRedirectRejected struct {
+	reason string
+}
+
+func (e *errRedirectRejected) Error() string {
+	return e.reason
+}
+
+// parseExpectNoRedirect reads 'expectNoRedirect'. Redirects are then not
+// followed at all, so the keys that shape how they are followed are
+// rejected rather than ignored.
+func parseExpectNoRedirect(config map[string]string) (bool, error) {
+	expectNoRedirect, err := configBool(config, "expectNoRedirect", false)
+	if err != nil || !expectNoRedirect {
+		return false, err
+	}
+	if key := firstKey(config, []string{"maxRedirects", "restrictRedirectHost", "noDowngradeRedirect", "expectedRedirectCount"}); key != "" {
+		return false, fmt.Errorf("'expectNoRedirect' cannot be combined with '%s'", key)
+	}
+	return true, nil
+}
+
+// newCheckRedirect returns the client's redirect policy. It is installed
+// even with the defaults: Go's own policy stops at the 10th redirect
+// rather than after it, which would make the default differ from an
+// explicit 'maxRedirects' of 10.
+func newCheckRedirect(config map[string]string, expectNoRedirect bool) (func(*http.Request, []*http.Request) error, error) {
+	// With 'expectNoRedirect' the redirect response itself is the result,
+	// so that noRedirectCriterion can report it.
+	if expectNoRedirect {
+		return func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}, nil
+	}
+
+	restrict, err := configBool(config, "restrictRedirectHost", false)
+	if err != nil {
+		return nil, err
+	}
+	maxRedirects, err := configInt(config, "maxRedirects", defaultMaxRedirects)
+	if err != nil {
+		return nil, err
+	}
+	if maxRedirects < 0 {
+		return nil, fmt.Errorf("invalid 'maxRedirects' in config: must not be negative")
+	}
+	noDowngrade, err := configBool(config, "noDowngradeRedirect", false)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		// via holds the requests already sent, so this is redirect len(via)
+		if len(via) > maxRedirects {
+			return &errRedirectRejected{reason: fmt.Sprintf("stopped after %d redirects, the 'maxRedirects' limit", maxRedirects)}
+		}
+		if origin := via[0].URL.Host; restrict && !strings.EqualFold(req.URL.Host, origin) {
+			return &errRedirectRejected{reason: fmt.Sprintf("redirect from host %s to host %s rejected by 'restrictRedirectHost'", origin, req.URL.Host)}
+		}
+		if from := via[len(via)-1].URL; noDowngrade && from.Scheme == "https" && req.URL.Scheme != "https" {
+			return &errRedirectRejected{reason: fmt.Sprintf("redirect from %s to %s rejected by 'noDowngradeRedirect'", from.Redacted(), req.URL.Redacted())}
+		}
+		return nil
+	}, nil
+}
+
+// noRedirectCriterion fails when the response redirects, reporting where
+// to. 304 Not Modified is a 3xx status but not a redirect.
+func noRedirectCriterion(resp *http.Response) criterion {
+	cr := criterion{name: "expectNoRedirect"}
+	if resp.StatusCode < 300 || resp.StatusCode > 399 || resp.StatusCode == http.StatusNotModified {
+		cr.passed = true
+		cr.detail = fmt.Sprintf("served directly with status %d", resp.StatusCode)
+		return cr
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		location = "(no Location header)"
+	}
+	cr.detail = fmt.Sprintf("redirected with status %d to %s", resp.StatusCode, location)
+	return cr
+}
+
+// parseExpectedRedirectCount reads 'expectedRedirectCount'. It returns -1
+// when the key is absent.
+func parseExpectedRedirectCount(config map[string]string) (int, error) {
+	if _, ok := config["expectedRedirectCount"]; !ok {
+		return -1, nil
+	}
+	expected, err := configInt(config, "expectedRedirectCount", 0)
+	if err != nil {
+		return 0, err
+	}
+	if expected < 0 {
+		return 0, fmt.Errorf("invalid 'expectedRedirectCount' in config: must not be negative")
+	}
+	return expected, nil
+}
+
+// redirectChain returns the URLs followed to reach resp, starting with the
+// one requested. The client links each redirected request to the response
+// that caused it, so the chain is read back from resp.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil; {
+		chain = append(chain, req.URL.Redacted())
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// redirectCountCriterion checks the number of redirects followed, and
+// reports the whole chain when it is not the expected one.
+func redirectCountCriterion(expected int, chain []string) criterion {
+	count := len(chain) - 1
+	cr := criterion{name: "expectedRedirectCount", passed: count == expected, detail: fmt.Sprintf("%d redirect(s)", count)}
+	if !cr.passed {
+		cr.detail = fmt.Sprintf("%d redirect(s) (expected %d): %s", count, expected, strings.Join(chain, " -> "))
+	}
+	return cr
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRestrictRedirectHost(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer external.Close()
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/local":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/external":
+			http.Redirect(w, r, external.URL, http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		restrict    string
+		wantSuccess bool
+		wantReason  string
+	}{
+		{name: "same host allowed", path: "/local", restrict: "true", wantSuccess: true},
+		{name: "external host rejected", path: "/external", restrict: "true", wantReason: "redirect from host " + strings.TrimPrefix(server.URL, "http://") + " to host " + strings.TrimPrefix(external.URL, "http://") + " rejected"},
+		{name: "external host followed by default", path: "/external", restrict: "false", wantSuccess: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			output, err := runPlugin(t, map[string]string{
+				"uri":                  server.URL + tt.path,
+				"method":               "GET",
+				"restrictRedirectHost": tt.restrict,
+				"retries":              "2",
+				"retryBackoff":         "1ms",
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.FailureReason, tt.wantReason) {
+				t.Errorf("FailureReason = %q, want it to contain %q", output.FailureReason, tt.wantReason)
+			}
+			if !tt.wantSuccess && hits.Load() != 1 {
+				t.Errorf("Expected a rejected redirect not to be retried, got %d requests", hits.Load())
+			}
+		})
+	}
+}
+
+func TestMaxRedirects(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer external.Close()
+
+	// /hop/N redirects N more times before landing on /done
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/")); {
+		case r.URL.Path == "/done":
+		case r.URL.Path == "/away":
+			http.Redirect(w, r, external.URL, http.StatusFound)
+		case n > 1:
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+		case n == 1:
+			http.Redirect(w, r, "/done", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		config      map[string]string
+		wantSuccess bool
+		wantReason  string
+	}{
+		{name: "within limit", path: "/hop/3", config: map[string]string{"maxRedirects": "3"}, wantSuccess: true},
+		{name: "over limit", path: "/hop/4", config: map[string]string{"maxRedirects": "3"}, wantReason: "stopped after 3 redirects, the 'maxRedirects' limit"},
+		{name: "no redirects", path: "/hop/1", config: map[string]string{"maxRedirects": "0"}, wantReason: "stopped after 0 redirects"},
+		{name: "default limit", path: "/hop/10", wantSuccess: true},
+		{name: "over default limit", path: "/hop/11", wantReason: "stopped after 10 redirects"},
+		{name: "raised limit", path: "/hop/15", config: map[string]string{"maxRedirects": "20"}, wantSuccess: true},
+		{name: "combined with restrictRedirectHost", path: "/away", config: map[string]string{"maxRedirects": "5", "restrictRedirectHost": "true"}, wantReason: "rejected by 'restrictRedirectHost'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess || !strings.Contains(output.FailureReason, tt.wantReason) {
+				t.Errorf("got success=%v reason=%q, want %v/%q", output.Success, output.FailureReason, tt.wantSuccess, tt.wantReason)
+			}
+		})
+	}
+
+	for _, raw := range []string{"-1", "many"} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "maxRedirects": raw}); err == nil {
+			t.Errorf("Expected error for maxRedirects %q", raw)
+		}
+	}
+}
+
+func TestNoDowngradeRedirect(t *testing.T) {
+	newReq := func(raw string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, raw, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name       string
+		config     map[string]string
+		via        []string
+		next       string
+		wantReason string
+	}{
+		{name: "downgrade rejected", config: map[string]string{"noDowngradeRedirect": "true"}, via: []string{"https://a.example.com/"}, next: "http://a.example.com/", wantReason: "redirect from https://a.example.com/ to http://a.example.com/ rejected by 'noDowngradeRedirect'"},
+		{name: "downgrade after hops rejected", config: map[string]string{"noDowngradeRedirect": "true"}, via: []string{"http://a.example.com/", "https://b.example.com/"}, next: "http://b.example.com/", wantReason: "rejected by 'noDowngradeRedirect'"},
+		{name: "upgrade allowed", config: map[string]string{"noDowngradeRedirect": "true"}, via: []string{"http://a.example.com/"}, next: "https://a.example.com/"},
+		{name: "https allowed", config: map[string]string{"noDowngradeRedirect": "true"}, via: []string{"https://a.example.com/"}, next: "https://b.example.com/"},
+		{name: "host restriction still applies", config: map[string]string{"noDowngradeRedirect": "true", "restrictRedirectHost": "true"}, via: []string{"https://a.example.com/"}, next: "https://b.example.com/", wantReason: "rejected by 'restrictRedirectHost'"},
+		{name: "redirect limit still applies", config: map[string]string{"noDowngradeRedirect": "true", "maxRedirects": "1"}, via: []string{"https://a.example.com/", "https://a.example.com/1"}, next: "https://a.example.com/2", wantReason: "'maxRedirects' limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := newCheckRedirect(tt.config, false)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var via []*http.Request
+			for _, raw := range tt.via {
+				via = append(via, newReq(raw))
+			}
+			err = check(newReq(tt.next), via)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("Expected the redirect to be followed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantReason) {
+				t.Errorf("Error = %v, want it to contain %q", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestExpectNoRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "https://new.example.com/", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "expectNoRedirect": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !strings.Contains(output.Message, "[PASS] expectNoRedirect: served directly with status 200") {
+		t.Errorf("Expected success, got %+v", output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/old", "method": "GET", "expectNoRedirect": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "[FAIL] expectNoRedirect: redirected with status 301 to https://new.example.com/") {
+		t.Errorf("Expected the redirect to be reported, got %+v", output)
+	}
+
+	for _, config := range []map[string]string{
+		{"expectNoRedirect": "maybe"},
+		{"expectNoRedirect": "true", "maxRedirects": "3"},
+		{"expectNoRedirect": "true", "restrictRedirectHost": "true"},
+		{"expectNoRedirect": "true", "noDowngradeRedirect": "true"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestExpectedRedirectCount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL + "/a", "method": "GET", "expectedRedirectCount": "2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.RedirectCount != 2 || !strings.Contains(output.Message, "[PASS] expectedRedirectCount: 2 redirect(s)") {
+		t.Errorf("Expected two redirects, got %+v", output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/a", "method": "GET", "expectedRedirectCount": "1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chain := server.URL + "/a -> " + server.URL + "/b -> " + server.URL + "/c"
+	if output.Success || !strings.Contains(output.Message, "[FAIL] expectedRedirectCount: 2 redirect(s) (expected 1): "+chain) {
+		t.Errorf("Expected the chain to be reported, got %+v", output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/a", "method": "GET", "expectedRedirectCount": "2", "expectNoRedirect": "false"})
+	if err != nil || !output.Success {
+		t.Errorf("Expected a disabled expectNoRedirect to be accepted, got %v %+v", err, output)
+	}
+
+	output, err = runPlugin(t, map[string]string{"uri": server.URL + "/c", "method": "GET", "expectedRedirectCount": "0"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.RedirectCount != 0 {
+		t.Errorf("Expected no redirects, got %+v", output)
+	}
+
+	for _, config := range []map[string]string{
+		{"expectedRedirectCount": "-1"},
+		{"expectedRedirectCount": "two"},
+		{"expectedRedirectCount": "0", "expectNoRedirect": "true"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ---- Request Construction ----
+
+// buildRequest assembles the outbound request for target from config.
+func buildRequest(ctx context.Context, config map[string]string, method string, target *url.URL) (*http.Request, error) {
+	req, err := newBaseRequest(ctx, config, method, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyRequestHeaders(req, config); err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	if encoding := config["compressRequest"]; encoding != "" {
+		if err := compressBody(req, config, encoding); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// closeBody releases the body of a request that will not be sent, such as
+// an open 'bodyFile'.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// compressBody replaces the request body with its 'compressRequest'
+// encoding. Content-Length is set to the compressed size, and retries
+// resend the compressed bytes.
+func compressBody(req *http.Request, config map[string]string, encoding string) error {
+	if encoding != "gzip" {
+		return fmt.Errorf("invalid 'compressRequest' %q: expected gzip", encoding)
+	}
+	if _, ok := config["rawRequest"]; ok {
+		return fmt.Errorf("'compressRequest' cannot be used with 'rawRequest', which is sent as captured")
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return fmt.Errorf("'compressRequest' requires a request body")
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.Copy(zw, req.Body); err != nil {
+		return fmt.Errorf("failed to compress request body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress request body: %w", err)
+	}
+	req.Body.Close()
+
+	data := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Encoding", encoding)
+	return nil
+}
+
+// bodyKeys lists the mutually exclusive ways of providing a request body.
+var bodyKeys = []string{"rawRequest", "body", "bodyBase64", "bodyFile", "multipart", "graphqlQuery"}
+
+// newBaseRequest creates the request and its body, either from a raw
+// captured request or from the structured config keys.
+func newBaseRequest(ctx context.Context, config map[string]string, method string, target *url.URL) (*http.Request, error) {
+	var set []string
+	for _, key := range bodyKeys {
+		if _, ok := config[key]; ok {
+			set = append(set, "'"+key+"'")
+		}
+	}
+	if len(set) > 1 {
+		return nil, fmt.Errorf("only one request body source may be set, got %s", strings.Join(set, ", "))
+	}
+
+	if raw, ok := config["rawRequest"]; ok {
+		return parseRawRequest(ctx, raw, target)
+	}
+
+	var body io.Reader
+	var contentType string
+	if raw, ok := config["body"]; ok {
+		body = strings.NewReader(raw)
+		if slices.Contains(webDAVMethods, method) {
+			// WebDAV and CalDAV request bodies are XML documents
+			contentType = "application/xml; charset=utf-8"
+		}
+	}
+	if raw, ok := config["bodyBase64"]; ok {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'bodyBase64' in config: %w", err)
+		}
+		body, contentType = bytes.NewReader(data), "application/octet-stream"
+	}
+	if query, ok := config["graphqlQuery"]; ok {
+		data, err := buildGraphQLBody(query, config["graphqlVariables"])
+		if err != nil {
+			return nil, err
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	}
+	if raw, ok := config["multipart"]; ok {
+		buf, ct, err := buildMultipartBody(raw)
+		if err != nil {
+			return nil, err
+		}
+		body, contentType = buf, ct
+	}
+	path, isFile := config["bodyFile"]
+	var size int64
+	if isFile {
+		file, info, err := openBodyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		body, size, contentType = file, info.Size(), "application/octet-stream"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		if isFile {
+			body.(io.Closer).Close()
+		}
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if isFile {
+		// The file is streamed rather than buffered, and re-opened for
+		// every retry, warm-up or sample.
+		req.ContentLength = size
+		req.GetBody = func() (io.ReadCloser, error) {
+			file, _, err := openBodyFile(path)
+			return file, err
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// openBodyFile opens the 'bodyFile' to stream as the request body.
+func openBodyFile(path string) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open 'bodyFile': %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to open 'bodyFile': %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, fmt.Errorf("'bodyFile' %s is not a regular file", path)
+	}
+	return file, info, nil
+}
+
+// applyRequestHeaders sets the custom 'headers' from config, followed by
+// the headers derived from dedicated config keys.
+func applyRequestHeaders(req *http.Request, config map[string]string) error {
+	if raw, ok := config["headers"]; ok {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			return fmt.Errorf("invalid 'headers' in config: %w", err)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+	}
+
+	if host := config["hostHeader"]; host != "" {
+		// Go sends req.Host on the wire and ignores a Host entry in
+		// req.Header, so the virtual host must be set here.
+		req.Host = host
+	}
+
+	if override := config["methodOverride"]; override != "" {
+		req.Header.Set(methodOverrideHeader, strings.ToUpper(override))
+	}
+
+	if err := applyAuth(req, config); err != nil {
+		return err
+	}
+	sse, err := configBool(config, "sse", false)
+	if err != nil {
+		return err
+	}
+	if accept := config["accept"]; accept != "" {
+		req.Header.Set("Accept", accept)
+	} else if req.Header.Get("Accept") == "" {
+		// Ask content-negotiating servers for the format that is read
+		if sse {
+			req.Header.Set("Accept", "text/event-stream")
+		} else if firstKey(config, []string{"jsonPath", "jq"}) != "" {
+			req.Header.Set("Accept", "application/json")
+		} else if config["xmlPath"] != "" {
+			req.Header.Set("Accept", "application/xml")
+		}
+	}
+	if err := applyConditionalHeaders(req, config); err != nil {
+		return err
+	}
+
+	if key := config["idempotencyKey"]; key != "" {
+		// The key is generated once per Run, so retried attempts (which
+		// clone this request) reuse it and stay deduplicated server-side.
+		if key == "auto" {
+			uuid, err := newUUID()
+			if err != nil {
+				return fmt.Errorf("failed to generate idempotency key: %w", err)
+			}
+			key = uuid
+		}
+		header := config["idempotencyKeyHeader"]
+		if header == "" {
+			header = "Idempotency-Key"
+		}
+		req.Header.Set(header, key)
+	}
+	return nil
+}
+
+// standardMethods are the methods of RFC 9110 and RFC 5789 (PATCH).
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// webDAVMethods default a 'body' to an XML Content-Type.
+var webDAVMethods = []string{"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK", "REPORT", "MKCALENDAR", "SEARCH"}
+
+// normalizeMethod uppercases method when it names a standard method, so
+// 'get' is sent as GET and matches the checks made on the method later.
+// Extension methods are case-sensitive and left as they are.
+func normalizeMethod(method string) string {
+	if upper := strings.ToUpper(method); slices.Contains(standardMethods, upper) {
+		return upper
+	}
+	return method
+}
+
+// checkMethod accepts the standard methods, and extension methods such as
+// PROPFIND only when they are listed in 'allowExtensionMethods', so a typo
+// in 'method' is reported instead of sent. The method must already be
+// normalized.
+func checkMethod(config map[string]string, method string) error {
+	if slices.Contains(standardMethods, method) {
+		return nil
+	}
+	for _, allowed := range configList(config, "allowExtensionMethods") {
+		if !httpguts.ValidHeaderFieldName(allowed) {
+			return fmt.Errorf("invalid 'allowExtensionMethods' in config: %q is not a valid method", allowed)
+		}
+		if allowed == method {
+			return nil
+		}
+	}
+	return fmt.Errorf("'method' %q is not a standard HTTP method; list it in 'allowExtensionMethods' to send it", method)
+}
+
+// methodOverrideHeader tells backends which method a POST stands in for.
+const methodOverrideHeader = "X-HTTP-Method-Override"
+
+// methodForOverride validates 'methodOverride' and returns the method
+// actually sent, which is always POST: the override only makes sense
+// for verbs that are blocked at the edge, so 'method' must be POST or
+// left unset, and the override must name a different method.
+func methodForOverride(config map[string]string, method, override string) (string, error) {
+	if _, ok := config["rawRequest"]; ok {
+		return "", fmt.Errorf("'methodOverride' cannot be used with 'rawRequest'")
+	}
+	if method != "" && method != http.MethodPost {
+		return "", fmt.Errorf("'methodOverride' sends a POST, but 'method' is %s", method)
+	}
+	if strings.EqualFold(override, http.MethodPost) {
+		return "", fmt.Errorf("'methodOverride' POST is the method actually sent; remove it")
+	}
+	return http.MethodPost, nil
+}
+
+// defaultCorrelationIDHeader carries the correlation ID when only
+// 'verifyCorrelationEcho' is set.
+const defaultCorrelationIDHeader = "X-Request-Id"
+
+// applyCorrelationID injects a freshly generated correlation ID when
+// 'correlationIdHeader' or 'verifyCorrelationEcho' is set, and returns the
+// header name and ID. Retries clone the request and so keep the same ID.
+func applyCorrelationID(req *http.Request, config map[string]string) (header, id string, err error) {
+	header, named := config["correlationIdHeader"]
+	if _, verify := config["verifyCorrelationEcho"]; !named && !verify {
+		return "", "", nil
+	}
+	if header == "" {
+		header = defaultCorrelationIDHeader
+	}
+	if id, err = newUUID(); err != nil {
+		return "", "", fmt.Errorf("failed to generate correlation ID: %w", err)
+	}
+	req.Header.Set(header, id)
+	return header, id, nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// buildGraphQLBody wraps a query and its optional JSON variables into the
+// standard GraphQL-over-HTTP request body.
+func buildGraphQLBody(query, variables string) ([]byte, error) {
+	payload := struct {
+		Query     string          `json:"query"`
+		Variables json.RawMessage `json:"variables,omitempty"`
+	}{Query: query}
+
+	if variables != "" {
+		if !json.Valid([]byte(variables)) {
+			return nil, fmt.Errorf("invalid 'graphqlVariables' in config: not valid JSON")
+		}
+		payload.Variables = json.RawMessage(variables)
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key")+"|"+r.Header.Get("X-Dedupe"))
+		n := len(keys)
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":            server.URL,
+		"method":         "POST",
+		"idempotencyKey": "auto",
+		"retries":        "1",
+		"retryBackoff":   "1ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Fatalf("Expected successful response, got: %v", output.Message)
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\|$`)
+	if len(keys) != 2 || !uuid.MatchString(keys[0]) || keys[0] != keys[1] {
+		t.Errorf("Expected the same generated UUID on every attempt, got %q", keys)
+	}
+
+	keys = nil
+	if _, err := runPlugin(t, map[string]string{
+		"uri":                  server.URL,
+		"method":               "POST",
+		"idempotencyKey":       "fixed-key",
+		"idempotencyKeyHeader": "X-Dedupe",
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "|fixed-key" {
+		t.Errorf("Expected literal key in X-Dedupe, got %q", keys)
+	}
+}
+
+func TestAcceptHeader(t *testing.T) {
+	var gotAccept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAccept = r.Header.Get("Accept")
+	}))
+	defer server.Close()
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotAccept != "" {
+		t.Errorf("Expected no Accept header by default, got %q", gotAccept)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "accept": "application/xml"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotAccept != "application/xml" {
+		t.Errorf("Accept = %q, want application/xml", gotAccept)
+	}
+}
+
+func TestRequestBodies(t *testing.T) {
+	var gotBody []byte
+	var gotType, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotType, gotHeader = r.Header.Get("Content-Type"), r.Header.Get("X-Tenant")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		config   map[string]string
+		wantBody []byte
+		wantType string
+	}{
+		{
+			name:     "text body",
+			config:   map[string]string{"body": `{"ping": true}`, "headers": `{"Content-Type": "application/json", "X-Tenant": "canary"}`},
+			wantBody: []byte(`{"ping": true}`),
+			wantType: "application/json",
+		},
+		{
+			name:     "base64 body",
+			config:   map[string]string{"bodyBase64": "AAH/fw=="},
+			wantBody: []byte{0x00, 0x01, 0xff, 0x7f},
+			wantType: "application/octet-stream",
+		},
+		{
+			name:     "base64 body with content type override",
+			config:   map[string]string{"bodyBase64": "CgVoZWxsbw==", "headers": `{"content-type": "application/x-protobuf"}`},
+			wantBody: []byte("\n\x05hello"),
+			wantType: "application/x-protobuf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL, "method": "POST"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			if _, err := runPlugin(t, config); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Equal(gotBody, tt.wantBody) || gotType != tt.wantType {
+				t.Errorf("Got body %q (%s), want %q (%s)", gotBody, gotType, tt.wantBody, tt.wantType)
+			}
+		})
+	}
+	if gotHeader != "" {
+		t.Errorf("Custom header leaked between runs: %q", gotHeader)
+	}
+
+	for _, config := range []map[string]string{
+		{"bodyBase64": "not base64!"},
+		{"body": "text", "bodyBase64": "AAE="},
+		{"headers": `["X-Tenant"]`},
+	} {
+		config["uri"] = server.URL
+		config["method"] = "POST"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id") + r.Header.Get("X-Trace")
+		received = append(received, id)
+		if r.URL.Path == "/echo" {
+			w.Header().Set("X-Request-Id", id)
+		}
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	plugin := &HTTPPlugin{Logger: hclog.New(&hclog.LoggerOptions{Output: &logs})}
+	input, _ := json.Marshal(PluginInput{Config: map[string]string{"uri": server.URL + "/echo", "method": "GET", "verifyCorrelationEcho": "true"}})
+	raw, err := plugin.Run(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var output PluginOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		t.Fatalf("Failed to unmarshal output: %v", err)
+	}
+	if !output.Success || !strings.Contains(output.Message, "[PASS] correlationEcho") {
+		t.Errorf("Expected the echoed ID to pass, got: %v", output.Message)
+	}
+	if output.CorrelationID == "" || len(received) != 1 || received[0] != output.CorrelationID {
+		t.Errorf("CorrelationID = %q, server received %q", output.CorrelationID, received)
+	}
+	if !strings.Contains(logs.String(), "correlationId="+output.CorrelationID) {
+		t.Errorf("Expected the correlation ID in logs, got %q", logs.String())
+	}
+
+	// Without an echo the check fails
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "verifyCorrelationEcho": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "header X-Request-Id is missing") {
+		t.Errorf("Expected a missing echo to fail, got: %v", output.Message)
+	}
+
+	// A custom header only injects the ID
+	received = nil
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "correlationIdHeader": "X-Trace"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || len(received) != 1 || received[0] != output.CorrelationID || output.CorrelationID == "" {
+		t.Errorf("Expected the ID in X-Trace, got output %+v, received %q", output, received)
+	}
+
+	// Nothing is injected by default
+	received = nil
+	output, _ = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET"})
+	if output.CorrelationID != "" || len(received) != 1 || received[0] != "" {
+		t.Errorf("Expected no correlation ID by default, got %q", received)
+	}
+}
+
+func TestMethodOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.Header.Get("X-HTTP-Method-Override")))
+	}))
+	defer server.Close()
+
+	for _, config := range []map[string]string{
+		{"methodOverride": "patch"},
+		{"methodOverride": "DELETE", "method": "POST"},
+	} {
+		config["uri"] = server.URL
+		output, err := runPlugin(t, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := "Body: POST " + strings.ToUpper(config["methodOverride"])
+		if !output.Success || !strings.HasSuffix(output.Message, want) {
+			t.Errorf("config %v: Message = %q, want suffix %q", config, output.Message, want)
+		}
+	}
+
+	for _, config := range []map[string]string{
+		{"methodOverride": "DELETE", "method": "GET"},
+		{"methodOverride": "post"},
+		{"methodOverride": "DELETE", "rawRequest": "GET / HTTP/1.1\r\nHost: x\r\n\r\n"},
+	} {
+		config["uri"] = server.URL
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestHostHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "hostHeader": "canary.example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(output.Message, "Body: canary.example.com") {
+		t.Errorf("Message = %q, want the virtual host echoed", output.Message)
+	}
+}
+
+func TestCompressRequest(t *testing.T) {
+	payload := strings.Repeat(`{"item": "canary"}`, 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Encoding") != "gzip" || r.ContentLength != int64(len(compressed)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(zr)
+		if string(body) != payload || len(compressed) >= len(payload) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "POST", "body": payload, "compressRequest": "gzip"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected the server to accept the compressed body: %s", output.Message)
+	}
+
+	for _, config := range []map[string]string{
+		{"body": payload, "compressRequest": "br"},
+		{"compressRequest": "gzip"},
+		{"rawRequest": "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 2\r\n\r\nhi", "compressRequest": "gzip"},
+	} {
+		config["uri"], config["method"] = server.URL, "POST"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestExtensionMethods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(207)
+		w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	propfind := `<?xml version="1.0"?><propfind xmlns="DAV:"><allprop/></propfind>`
+	tests := []struct {
+		config map[string]string
+		want   string
+	}{
+		{
+			config: map[string]string{"method": "PROPFIND", "allowExtensionMethods": "PROPFIND, REPORT", "body": propfind},
+			want:   "Body: PROPFIND application/xml; charset=utf-8 " + propfind,
+		},
+		{
+			config: map[string]string{"method": "REPORT", "allowExtensionMethods": "PROPFIND,REPORT", "body": "<x/>", "headers": `{"Content-Type": "text/xml"}`},
+			want:   "Body: REPORT text/xml <x/>",
+		},
+		{
+			config: map[string]string{"method": "MKCOL", "allowExtensionMethods": "MKCOL"},
+			want:   "Body: MKCOL  ",
+		},
+	}
+	for _, tt := range tests {
+		tt.config["uri"] = server.URL
+		output, err := runPlugin(t, tt.config)
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", tt.config, err)
+		}
+		if !strings.HasSuffix(output.Message, tt.want) {
+			t.Errorf("config %v: Message = %q, want suffix %q", tt.config, output.Message, tt.want)
+		}
+	}
+
+	// Standard methods are matched and sent in upper case
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "get"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(output.Message, "Body: GET  ") {
+		t.Errorf("Expected a GET request, got %q", output.Message)
+	}
+
+	for _, config := range []map[string]string{
+		{"method": "head", "bodyMatch": "ok"},
+		{"method": "PROPFIND"},
+		{"method": "PROPFIND", "allowExtensionMethods": "REPORT"},
+		{"method": "GE T", "allowExtensionMethods": "GE T"},
+	} {
+		config["uri"] = server.URL
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestBodyFile(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	path := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The body is the file itself, streamed by the client, not a buffer
+	target, _ := url.Parse("http://127.0.0.1")
+	req, err := buildRequest(context.Background(), map[string]string{"bodyFile": path}, http.MethodPut, target)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if _, ok := req.Body.(*os.File); !ok {
+		t.Errorf("Body is %T, want *os.File", req.Body)
+	}
+	closeBody(req)
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(payload)) || !bytes.Equal(body, payload) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The first attempt fails, so the retry must re-open the file
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":          server.URL,
+		"method":       "PUT",
+		"bodyFile":     path,
+		"retries":      "1",
+		"retryBackoff": "1ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || attempts.Load() != 2 {
+		t.Errorf("Got success=%v after %d attempts, want success on the retry: %.200s", output.Success, attempts.Load(), output.Message)
+	}
+
+	for _, config := range []map[string]string{
+		{"bodyFile": filepath.Join(t.TempDir(), "missing.bin")},
+		{"bodyFile": t.TempDir()},
+		{"bodyFile": path, "body": "inline"},
+	} {
+		config["uri"], config["method"] = server.URL, "PUT"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestResponseFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("canary response"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "response.txt")
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "responseFile": path})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !strings.Contains(output.Message, "canary response") {
+		t.Errorf("Expected body to still be returned, got: %v", output.Message)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "canary response" {
+		t.Errorf("Response file = %q, %v; want the response body", data, err)
+	}
+}
+
+func TestResponseFileWriteFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	badPath := filepath.Join(t.TempDir(), "missing-dir", "response.txt")
+
+	// By default a write failure is only logged
+	var logs bytes.Buffer
+	plugin := &HTTPPlugin{Logger: hclog.New(&hclog.LoggerOptions{Output: &logs})}
+	input, _ := json.Marshal(PluginInput{Config: map[string]string{"uri": server.URL, "method": "GET", "responseFile": badPath}})
+	raw, err := plugin.Run(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var output PluginOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		t.Fatalf("Failed to unmarshal output: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected write failure to be non-fatal, got: %v", output.Message)
+	}
+	if !strings.Contains(logs.String(), "failed to write response file") {
+		t.Errorf("Expected warning in logs, got %q", logs.String())
+	}
+
+	// With responseFileRequired the step fails
+	output, err = runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "responseFile": badPath, "responseFileRequired": "true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || !strings.Contains(output.Message, "Response file error") {
+		t.Errorf("Expected required write failure to fail the step, got: %v", output.Message)
+	}
+}
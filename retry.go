@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---- Retry Policy ----
+
+// retryPolicy controls how often a request is re-attempted and which
+// responses are treated as transient failures.
+type retryPolicy struct {
+	retries    int
+	duration   time.Duration // with 'retryDuration', replaces retries
+	backoff    time.Duration
+	maxBackoff time.Duration // 0 means uncapped
+	statuses   map[int]bool  // nil means "retry on any 5xx"
+	// ignoreRetryAfter turns off honouring Retry-After on 429 and 503
+	ignoreRetryAfter bool
+	// bodyMismatch, with 'retryOnBodyMismatch', reports whether a response
+	// whose status is not retried should be retried for its body.
+	bodyMismatch func(resp *http.Response, body []byte) bool
+}
+
+func parseRetryPolicy(config map[string]string) (retryPolicy, error) {
+	retries, err := configInt(config, "retries", 0)
+	if err != nil {
+		return retryPolicy{}, err
+	}
+	if retries < 0 {
+		return retryPolicy{}, fmt.Errorf("invalid 'retries' in config: must not be negative")
+	}
+
+	duration, err := configDuration(config, "retryDuration", 0)
+	if err != nil {
+		return retryPolicy{}, err
+	}
+	if duration < 0 {
+		return retryPolicy{}, fmt.Errorf("invalid 'retryDuration' in config: must not be negative")
+	}
+	if duration > 0 && retries > 0 {
+		return retryPolicy{}, fmt.Errorf("'retryDuration' cannot be combined with 'retries'")
+	}
+
+	backoff, err := configDuration(config, "retryBackoff", time.Second)
+	if err != nil {
+		return retryPolicy{}, err
+	}
+	if backoff < 0 {
+		return retryPolicy{}, fmt.Errorf("invalid 'retryBackoff' in config: must not be negative")
+	}
+
+	maxBackoff, err := configDuration(config, "retryMaxBackoff", 0)
+	if err != nil {
+		return retryPolicy{}, err
+	}
+	if maxBackoff < 0 {
+		return retryPolicy{}, fmt.Errorf("invalid 'retryMaxBackoff' in config: must not be negative")
+	}
+
+	retryAfter, err := configBool(config, "respectRetryAfter", true)
+	if err != nil {
+		return retryPolicy{}, err
+	}
+
+	policy := retryPolicy{retries: retries, duration: duration, backoff: backoff, maxBackoff: maxBackoff, ignoreRetryAfter: !retryAfter}
+	if codes := configList(config, "retryOnStatus"); len(codes) > 0 {
+		policy.statuses = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			status, err := strconv.Atoi(code)
+			if err != nil || status < 100 || status > 599 {
+				return retryPolicy{}, fmt.Errorf("invalid status code %q in 'retryOnStatus'", code)
+			}
+			policy.statuses[status] = true
+		}
+	}
+	return policy, nil
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying.
+// Transport errors are retried unless a redirect was rejected; responses
+// are retried when their status is listed in retryOnStatus, or is a 5xx
+// when no list was given.
+func (p retryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		var rejected *errRedirectRejected
+		return !errors.As(err, &rejected)
+	}
+	if p.statuses != nil {
+		return p.statuses[resp.StatusCode]
+	}
+	return resp.StatusCode >= 500
+}
+
+// maxRetryBackoff bounds the exponential backoff, so that doubling it for
+// many retries saturates instead of overflowing.
+const maxRetryBackoff = time.Hour
+
+// delay returns how long to wait before the given retry (1-based). Unless
+// 'respectRetryAfter' is off, a Retry-After header on a 429 or 503
+// response takes precedence over the exponential backoff. Either is capped
+// by 'retryMaxBackoff'.
+func (p retryPolicy) delay(retry int, resp *http.Response) time.Duration {
+	wait := maxRetryBackoff
+	if shift := retry - 1; p.backoff <= maxRetryBackoff>>shift {
+		wait = p.backoff << shift
+	}
+	if !p.ignoreRetryAfter && resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			wait = d
+		}
+	}
+	if p.maxBackoff > 0 {
+		wait = min(wait, p.maxBackoff)
+	}
+	return wait
+}
+
+// parseRetryAfter reads a Retry-After value in either of its forms, a
+// number of seconds or an HTTP date. A date in the past means no wait.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// allowsRetry reports whether another attempt may follow the given number
+// of attempts, made over elapsed time.
+func (p retryPolicy) allowsRetry(attempts int, elapsed time.Duration) bool {
+	if p.duration > 0 {
+		return elapsed < p.duration
+	}
+	return attempts <= p.retries
+}
+
+// doWithRetry sends req, retrying according to policy. Retried attempts
+// use a clone of req whose body is re-opened through GetBody.
+func doWithRetry(ctx context.Context, client *http.Client, policy retryPolicy, req *http.Request) (*http.Response, error) {
+	resp, _, err := doWithRetryCount(ctx, client, policy, req)
+	return resp, err
+}
+
+// doWithRetryCount is doWithRetry that also returns the number of attempts
+// made.
+func doWithRetryCount(ctx context.Context, client *http.Client, policy retryPolicy, req *http.Request) (*http.Response, int, error) {
+	start := time.Now()
+	for attempts := 1; ; attempts++ {
+		resp, err := client.Do(req)
+		retry := policy.shouldRetry(resp, err)
+		if !retry && err == nil && policy.bodyMismatch != nil {
+			retry = policy.retryForBody(resp)
+		}
+		if !policy.allowsRetry(attempts, time.Since(start)) || !retry {
+			return resp, attempts, err
+		}
+
+		wait := policy.delay(attempts, resp)
+		if policy.duration > 0 {
+			// The last attempt is made when 'retryDuration' runs out
+			wait = min(wait, policy.duration-time.Since(start))
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, attempts, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		if req, err = cloneRequest(ctx, req); err != nil {
+			return nil, attempts, err
+		}
+	}
+}
+
+// retryForBody reads the body of resp to check it with bodyMismatch, then
+// puts it back so the caller can read it again. A body that fails to read
+// is not retried; the caller sees the same error.
+func (p retryPolicy) retryForBody(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var rest io.Reader = bytes.NewReader(body)
+	if err != nil {
+		rest = io.MultiReader(rest, &errorReader{err: err})
+	}
+	resp.Body = io.NopCloser(rest)
+	return err == nil && p.bodyMismatch(resp, body)
+}
+
+// errorReader fails every read with err.
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// reportRetries records the attempts made with 'retryDuration' or
+// 'retryOnBodyMismatch' and the time they took.
+func (o *PluginOutput) reportRetries(mode string, attempts int, elapsed time.Duration) {
+	o.RequestAttempts, o.ElapsedMs = attempts, milliseconds(elapsed)
+	if mode != outputModeMinimal {
+		o.Message += fmt.Sprintf("\nAttempts: %d in %s", attempts, elapsed.Round(time.Millisecond))
+	}
+}
+
+// cloneRequest returns a copy of req that can be sent again, with its body
+// re-opened through GetBody.
+func cloneRequest(ctx context.Context, req *http.Request) (*http.Request, error) {
+	next := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		next.Body = body
+	}
+	return next, nil
+}
+
+// errBudgetExhausted is the cancellation cause recorded when the
+// 'maxTotalDuration' budget for a whole Run runs out.
+type errBudgetExhausted struct {
+	budget time.Duration
+}
+
+func (e *errBudgetExhausted) Error() string {
+	return fmt.Sprintf("maxTotalDuration of %s exhausted", e.budget)
+}
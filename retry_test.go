@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryOnStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []int
+		config      map[string]string
+		wantHits    int32
+		wantSuccess bool
+	}{
+		{
+			name:        "no retries by default",
+			statuses:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			config:      map[string]string{},
+			wantHits:    1,
+			wantSuccess: false,
+		},
+		{
+			name:        "default retries 5xx",
+			statuses:    []int{http.StatusBadGateway, http.StatusOK},
+			config:      map[string]string{"retries": "2"},
+			wantHits:    2,
+			wantSuccess: true,
+		},
+		{
+			name:        "default does not retry 4xx",
+			statuses:    []int{http.StatusTooManyRequests, http.StatusOK},
+			config:      map[string]string{"retries": "2"},
+			wantHits:    1,
+			wantSuccess: false,
+		},
+		{
+			name:        "retryOnStatus retries listed codes",
+			statuses:    []int{http.StatusTooManyRequests, http.StatusOK},
+			config:      map[string]string{"retries": "2", "retryOnStatus": "429"},
+			wantHits:    2,
+			wantSuccess: true,
+		},
+		{
+			name:        "retryOnStatus overrides 5xx rule",
+			statuses:    []int{http.StatusInternalServerError, http.StatusOK},
+			config:      map[string]string{"retries": "2", "retryOnStatus": "429, 503"},
+			wantHits:    1,
+			wantSuccess: false,
+		},
+		{
+			name:        "gives up after retries",
+			statuses:    []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			config:      map[string]string{"retries": "2"},
+			wantHits:    3,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := hits.Add(1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer server.Close()
+
+			config := map[string]string{"uri": server.URL, "method": "GET", "retryBackoff": "1ms"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	policy := retryPolicy{retries: 3, backoff: time.Second}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"0"}}}
+	if got := policy.delay(1, resp); got != 0 {
+		t.Errorf("delay with Retry-After: 0 = %v, want 0", got)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusInternalServerError, Header: http.Header{"Retry-After": {"0"}}}
+	if got := policy.delay(2, resp); got != 2*time.Second {
+		t.Errorf("delay for 500 = %v, want backoff of 2s", got)
+	}
+}
+
+func TestRetryAfterForms(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: " 0 ", want: 0, wantOK: true},
+		{value: "Sun, 01 Jun 2025 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{value: "Sun, 01 Jun 2025 11:00:00 GMT", want: 0, wantOK: true},
+		{value: "-5"},
+		{value: "soon"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"3600"}}}
+	capped := retryPolicy{backoff: time.Second, maxBackoff: 5 * time.Second}
+	if got := capped.delay(1, resp); got != 5*time.Second {
+		t.Errorf("delay with retryMaxBackoff = %v, want 5s", got)
+	}
+	if got := capped.delay(4, nil); got != 5*time.Second {
+		t.Errorf("backoff with retryMaxBackoff = %v, want 5s", got)
+	}
+	for _, retry := range []int{13, 40, 64, 1000} {
+		if got := (retryPolicy{backoff: time.Second}).delay(retry, nil); got != maxRetryBackoff {
+			t.Errorf("backoff for retry %d = %v, want it to saturate at %v", retry, got, maxRetryBackoff)
+		}
+	}
+	ignored := retryPolicy{backoff: time.Second, ignoreRetryAfter: true}
+	if got := ignored.delay(1, resp); got != time.Second {
+		t.Errorf("delay with respectRetryAfter off = %v, want the 1s backoff", got)
+	}
+}
+
+func TestRespectRetryAfter(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	// The hour-long Retry-After date is capped, so the retry comes quickly
+	start := time.Now()
+	output, err := runPlugin(t, map[string]string{
+		"uri":             server.URL,
+		"method":          "GET",
+		"retries":         "1",
+		"retryOnStatus":   "429",
+		"retryMaxBackoff": "50ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || hits.Load() != 2 || time.Since(start) > 2*time.Second {
+		t.Errorf("Got success=%v after %d requests in %s", output.Success, hits.Load(), time.Since(start))
+	}
+}
+
+func TestInvalidRetryConfig(t *testing.T) {
+	for _, config := range []map[string]string{
+		{"retries": "many"},
+		{"retries": "-1"},
+		{"retryBackoff": "soon"},
+		{"retryBackoff": "-1s"},
+		{"retryOnStatus": "429,abc"},
+		{"retryDuration": "-1s"},
+		{"retryMaxBackoff": "-1s"},
+		{"respectRetryAfter": "sometimes"},
+		{"retryDuration": "1m", "retries": "3"},
+	} {
+		config["uri"] = "http://127.0.0.1"
+		config["method"] = "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestMaxTotalDuration(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	output, err := runPlugin(t, map[string]string{
+		"uri":              server.URL,
+		"method":           "GET",
+		"retries":          "10",
+		"retryBackoff":     "50ms",
+		"maxTotalDuration": "200ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run took %v, expected it to stop near the 200ms budget", elapsed)
+	}
+	if output.Success {
+		t.Error("Expected failure once the budget is exhausted")
+	}
+	if !strings.Contains(output.Message, "maxTotalDuration of 200ms exhausted") {
+		t.Errorf("Expected budget exhaustion in message, got: %v", output.Message)
+	}
+	if n := hits.Load(); n < 2 || n > 4 {
+		t.Errorf("server hits = %d, expected a few attempts within the budget", n)
+	}
+}
+
+func TestMaxTotalDurationWrappers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// Each config needs about 600ms without a shared budget
+	requests := fmt.Sprintf(`[{"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}, {"uri": "%[1]s"}]`, server.URL)
+	tests := map[string]map[string]string{
+		"consecutive": {"uri": server.URL, "requiredConsecutiveSuccesses": "4", "consecutiveInterval": "150ms"},
+		"batch":       {"requests": requests},
+		"matrix":      {"uri": server.URL, "matrix": `{"accept": ["a/1", "a/2", "a/3", "a/4", "a/5", "a/6", "a/7", "a/8", "a/9", "a/10", "a/11", "a/12"]}`},
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config["method"], config["maxTotalDuration"] = "GET", "200ms"
+			start := time.Now()
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+				t.Errorf("Run took %v, expected it to stop near the 200ms budget", elapsed)
+			}
+			if output.Success || !strings.Contains(output.FailureReason, "maxTotalDuration of 200ms exhausted") {
+				t.Errorf("Expected the budget to fail the run, got %+v", output)
+			}
+		})
+	}
+}
+
+func TestRetryDuration(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/recovers" && hits.Load() >= 3 {
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":           server.URL + "/recovers",
+		"method":        "GET",
+		"retryDuration": "5s",
+		"retryBackoff":  "10ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || output.RequestAttempts != 3 || output.ElapsedMs <= 0 {
+		t.Errorf("Got success=%v requestAttempts=%d elapsedMs=%v, want success after 3 attempts", output.Success, output.RequestAttempts, output.ElapsedMs)
+	}
+	if !strings.Contains(output.Message, "Attempts: 3 in ") {
+		t.Errorf("Message = %q, want the attempts reported", output.Message)
+	}
+
+	// Retries stop once the duration has elapsed, whatever the count
+	hits.Store(0)
+	start := time.Now()
+	output, err = runPlugin(t, map[string]string{
+		"uri":           server.URL + "/down",
+		"method":        "GET",
+		"retryDuration": "300ms",
+		"retryBackoff":  "20ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	elapsed := time.Since(start)
+	if output.Success || elapsed < 300*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Got success=%v after %s, want failure after about 300ms", output.Success, elapsed)
+	}
+	if int(hits.Load()) != output.RequestAttempts || output.RequestAttempts < 3 {
+		t.Errorf("requestAttempts = %d, server saw %d", output.RequestAttempts, hits.Load())
+	}
+}
+
+func TestRetryOnBodyMismatch(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		switch {
+		case r.URL.Path == "/down":
+			w.WriteHeader(http.StatusNotFound)
+		case n < 3:
+			w.Write([]byte(`{"state": "pending", "url": ""}`))
+		default:
+			w.Write([]byte(`{"state": "ready", "url": "https://example.com/report"}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		config       map[string]string
+		wantSuccess  bool
+		wantAttempts int
+	}{
+		{name: "body match", config: map[string]string{"bodyMatch": `"ready"`, "retries": "5"}, wantSuccess: true, wantAttempts: 3},
+		{name: "json path", config: map[string]string{"jsonPath": "url", "expectedValue": "regex:^https://", "retries": "5"}, wantSuccess: true, wantAttempts: 3},
+		{name: "retries exhausted", config: map[string]string{"bodyMatch": `"ready"`, "retries": "1"}, wantAttempts: 2},
+		{name: "within retry duration", config: map[string]string{"bodyMatch": `"ready"`, "retryDuration": "5s"}, wantSuccess: true, wantAttempts: 3},
+		{name: "status failure not retried for the body", path: "/down", config: map[string]string{"bodyMatch": `"ready"`, "retries": "5", "retryOnStatus": "500"}, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET", "retryOnBodyMismatch": "true", "retryBackoff": "1ms"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if output.RequestAttempts != tt.wantAttempts || int(hits.Load()) != tt.wantAttempts {
+				t.Errorf("RequestAttempts = %d with %d requests, want %d", output.RequestAttempts, hits.Load(), tt.wantAttempts)
+			}
+			if !strings.Contains(output.Message, "Attempts: ") {
+				t.Errorf("Message = %q, want the attempts", output.Message)
+			}
+		})
+	}
+
+	// Without the flag a mismatching body fails straight away
+	hits.Store(0)
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "bodyMatch": `"ready"`, "retries": "5", "retryBackoff": "1ms"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success || hits.Load() != 1 {
+		t.Errorf("Expected one failed attempt, got %d requests and %+v", hits.Load(), output)
+	}
+
+	for _, config := range []map[string]string{
+		{"retryOnBodyMismatch": "true"},
+		{"retryOnBodyMismatch": "yes", "bodyMatch": "x"},
+		{"retryOnBodyMismatch": "true", "bodyMatch": "x", "closeOnMatch": "true"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
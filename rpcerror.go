@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/rpc"
+	"runtime/debug"
+)
+
+// ---- RPC Errors ----
+
+// Codes of an RPCError.
+const (
+	// rpcErrorInvalidInput is an error returned by Run, such as invalid
+	// configuration.
+	rpcErrorInvalidInput = "InvalidInput"
+	// rpcErrorPanic is a panic in Run, recovered by the RPC server.
+	rpcErrorPanic = "Panic"
+)
+
+// RPCError is an error from the plugin's Run as received over RPC. net/rpc
+// only carries error text, so RPCServer sends it JSON-encoded and RPCClient
+// decodes it again.
+type RPCError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// encodeRPCError returns the error sent over RPC for err.
+func encodeRPCError(code string, err error) error {
+	data, marshalErr := json.Marshal(RPCError{Code: code, Message: err.Error()})
+	if marshalErr != nil {
+		return err
+	}
+	return errors.New(string(data))
+}
+
+// decodeRPCError reconstructs the RPCError in an error received over RPC.
+// Other errors, such as connection failures, are returned unchanged.
+func decodeRPCError(err error) error {
+	var serverErr rpc.ServerError
+	if !errors.As(err, &serverErr) {
+		return err
+	}
+	var rpcErr RPCError
+	if json.Unmarshal([]byte(serverErr), &rpcErr) != nil || rpcErr.Code == "" {
+		return err
+	}
+	return &rpcErr
+}
+
+// recoverRPCPanic turns a panic in Run into an rpcErrorPanic error stored
+// in *errp, so one bad input does not take down the plugin process. It
+// must be deferred.
+func recoverRPCPanic(errp *error) {
+	if r := recover(); r != nil {
+		log.Printf("Recovered from panic in Run: %v\n%s", r, debug.Stack())
+		*errp = encodeRPCError(rpcErrorPanic, fmt.Errorf("plugin panicked: %v", r))
+	}
+}
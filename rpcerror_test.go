@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/rpc"
+	"strings"
+	"testing"
+)
+
+// stepPluginFunc adapts a function to the StepPlugin interface.
+type stepPluginFunc func(ctx context.Context, rawInput json.RawMessage) (json.RawMessage, error)
+
+func (f stepPluginFunc) Run(ctx context.Context, rawInput json.RawMessage) (json.RawMessage, error) {
+	return f(ctx, rawInput)
+}
+
+// newRPCPair serves impl over an in-memory connection and returns the
+// client side.
+func newRPCPair(t *testing.T, impl StepPlugin) *RPCClient {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", &RPCServer{Impl: impl}); err != nil {
+		t.Fatalf("Failed to register server: %v", err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	client := rpc.NewClient(clientConn)
+	t.Cleanup(func() { client.Close() })
+	return &RPCClient{client: client}
+}
+
+func TestRPCErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		impl        StepPlugin
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "config error",
+			impl:        &HTTPPlugin{},
+			wantCode:    rpcErrorInvalidInput,
+			wantMessage: "missing 'uri' or 'method' in config",
+		},
+		{
+			name: "panic",
+			impl: stepPluginFunc(func(context.Context, json.RawMessage) (json.RawMessage, error) {
+				var config map[string]string
+				config["uri"] = "boom"
+				return nil, nil
+			}),
+			wantCode:    rpcErrorPanic,
+			wantMessage: "plugin panicked: assignment to entry in nil map",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newRPCPair(t, tt.impl)
+			_, err := client.Run(context.Background(), json.RawMessage(`{"config": {}}`))
+
+			var rpcErr *RPCError
+			if !errors.As(err, &rpcErr) {
+				t.Fatalf("Expected an *RPCError, got %T: %v", err, err)
+			}
+			if rpcErr.Code != tt.wantCode || !strings.Contains(rpcErr.Message, tt.wantMessage) {
+				t.Errorf("Got %+v, want code %s and message containing %q", rpcErr, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+
+	// The server keeps serving after a panic
+	client := newRPCPair(t, stepPluginFunc(func(_ context.Context, rawInput json.RawMessage) (json.RawMessage, error) {
+		if string(rawInput) == `"panic"` {
+			panic("bad input")
+		}
+		return rawInput, nil
+	}))
+	if _, err := client.Run(context.Background(), json.RawMessage(`"panic"`)); err == nil {
+		t.Fatal("Expected the panic to be reported")
+	}
+	if result, err := client.Run(context.Background(), json.RawMessage(`"ok"`)); err != nil || string(result) != `"ok"` {
+		t.Errorf("Run after panic = %s, %v", result, err)
+	}
+}
+
+func TestDecodeRPCErrorPassesOtherErrors(t *testing.T) {
+	for _, err := range []error{rpc.ErrShutdown, rpc.ServerError("plain text error")} {
+		if got := decodeRPCError(err); got != err {
+			t.Errorf("decodeRPCError(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ---- JSON Schema ----
+
+// compileResponseSchema compiles the JSON Schema given in 'responseSchema'.
+func compileResponseSchema(raw string) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'responseSchema' in config: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("responseSchema.json", doc); err != nil {
+		return nil, fmt.Errorf("invalid 'responseSchema' in config: %w", err)
+	}
+	schema, err := compiler.Compile("responseSchema.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'responseSchema' in config: %w", err)
+	}
+	return schema, nil
+}
+
+// schemaCriterion validates the JSON body against schema, listing every
+// violation with its location in the document.
+func schemaCriterion(schema *jsonschema.Schema, body []byte) criterion {
+	cr := criterion{name: "responseSchema"}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		cr.detail = fmt.Sprintf("response is not valid JSON: %v", err)
+		return cr
+	}
+
+	err = schema.Validate(doc)
+	var invalid *jsonschema.ValidationError
+	switch {
+	case err == nil:
+		cr.passed, cr.detail = true, "body conforms"
+	case errors.As(err, &invalid):
+		cr.detail = strings.Join(schemaViolations(invalid.BasicOutput()), "; ")
+	default:
+		cr.detail = err.Error()
+	}
+	return cr
+}
+
+// schemaViolations flattens validator output into "location: error" lines.
+func schemaViolations(unit *jsonschema.OutputUnit) []string {
+	var violations []string
+	for _, e := range unit.Errors {
+		if e.Error == nil {
+			continue
+		}
+		location := e.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		violations = append(violations, fmt.Sprintf("%s: %s", location, e.Error))
+	}
+	if len(violations) == 0 && unit.Error != nil {
+		violations = append(violations, unit.Error.String())
+	}
+	return violations
+}
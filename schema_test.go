@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseSchema(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"required": ["status", "replicas"],
+		"properties": {
+			"status": {"enum": ["ok", "degraded"]},
+			"replicas": {"type": "integer", "minimum": 1}
+		}
+	}`
+
+	tests := []struct {
+		name        string
+		body        string
+		wantSuccess bool
+		wantMessage string
+	}{
+		{
+			name:        "conforming body",
+			body:        `{"status": "ok", "replicas": 3}`,
+			wantSuccess: true,
+			wantMessage: "[PASS] responseSchema: body conforms",
+		},
+		{
+			name:        "wrong type",
+			body:        `{"status": "ok", "replicas": "three"}`,
+			wantMessage: "/replicas: got string, want integer",
+		},
+		{
+			name:        "missing property",
+			body:        `{"status": "ok"}`,
+			wantMessage: "missing property 'replicas'",
+		},
+		{
+			name:        "several violations",
+			body:        `{"status": "down", "replicas": 0}`,
+			wantMessage: "/status: ",
+		},
+		{
+			name:        "non-JSON body",
+			body:        `<html>ok</html>`,
+			wantMessage: "[FAIL] responseSchema: response is not valid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "responseSchema": schema})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestResponseSchemaInvalid(t *testing.T) {
+	for _, schema := range []string{`{"type": `, `{"type": "nonsense"}`} {
+		_, err := runPlugin(t, map[string]string{"uri": "http://localhost", "method": "GET", "responseSchema": schema})
+		if err == nil || !strings.Contains(err.Error(), "invalid 'responseSchema'") {
+			t.Errorf("schema %s: expected config error, got %v", schema, err)
+		}
+	}
+}
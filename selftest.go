@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// ---- Self-Test ----
+
+type selfTestCase struct {
+	name        string
+	config      map[string]string
+	timeout     time.Duration
+	wantSuccess bool
+}
+
+// runSelfTest exercises HTTPPlugin.Run end to end against a local
+// httptest.Server, writes a pass/fail line per case to w and reports
+// whether every case passed.
+func runSelfTest(w io.Writer) bool {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cases := []selfTestCase{
+		{name: "GET", config: map[string]string{"uri": server.URL + "/get", "method": "GET"}, wantSuccess: true},
+		{name: "POST", config: map[string]string{"uri": server.URL + "/post", "method": "POST"}, wantSuccess: true},
+		{name: "timeout", config: map[string]string{"uri": server.URL + "/slow", "method": "GET"}, timeout: 200 * time.Millisecond},
+		{name: "404", config: map[string]string{"uri": server.URL + "/missing", "method": "GET"}},
+	}
+
+	passed := 0
+	for _, tc := range cases {
+		if err := runSelfTestCase(tc); err != nil {
+			fmt.Fprintf(w, "FAIL  %-8s %v\n", tc.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "PASS  %s\n", tc.name)
+		passed++
+	}
+	fmt.Fprintf(w, "%d/%d self-test cases passed\n", passed, len(cases))
+	return passed == len(cases)
+}
+
+func runSelfTestCase(tc selfTestCase) error {
+	ctx := context.Background()
+	if tc.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+		defer cancel()
+	}
+
+	input, err := json.Marshal(PluginInput{Config: tc.config})
+	if err != nil {
+		return err
+	}
+	raw, err := (&HTTPPlugin{}).Run(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	var output PluginOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return err
+	}
+	if output.Success != tc.wantSuccess {
+		return fmt.Errorf("success = %v, want %v (%s)", output.Success, tc.wantSuccess, output.Message)
+	}
+	return nil
+}
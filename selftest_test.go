@@ -0,0 +1,17 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	var out bytes.Buffer
+	if !runSelfTest(&out) {
+		t.Fatalf("Self-test failed:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "4/4 self-test cases passed") {
+		t.Errorf("Unexpected summary:\n%s", out.String())
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ---- Sequence Assertions ----
+
+// sequenceKeys configure the batch as a whole, so entries do not inherit
+// them.
+var sequenceKeys = []string{"sequenceAssertion", "sequenceOrder"}
+
+// Values accepted by the 'sequenceOrder' key.
+const (
+	sequenceNonDecreasing = "nonDecreasing"
+	sequenceIncreasing    = "increasing"
+)
+
+// sequenceAssertion checks that the number at path in the body of each
+// batch response does not decrease, or with strict, always increases.
+type sequenceAssertion struct {
+	path   string
+	strict bool
+}
+
+func parseSequenceAssertion(config map[string]string) (*sequenceAssertion, error) {
+	path, ok := config["sequenceAssertion"]
+	if !ok {
+		if _, ok := config["sequenceOrder"]; ok {
+			return nil, fmt.Errorf("'sequenceOrder' requires 'sequenceAssertion'")
+		}
+		return nil, nil
+	}
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("invalid 'sequenceAssertion' in config: expected a JSON path")
+	}
+	s := &sequenceAssertion{path: path}
+	switch order := config["sequenceOrder"]; order {
+	case "", sequenceNonDecreasing:
+	case sequenceIncreasing:
+		s.strict = true
+	default:
+		return nil, fmt.Errorf("invalid 'sequenceOrder' %q: expected %s or %s", order, sequenceNonDecreasing, sequenceIncreasing)
+	}
+	return s, nil
+}
+
+// value extracts the number at the path from a JSON body. Numeric strings,
+// such as large counters, are accepted too.
+func (s *sequenceAssertion) value(body []byte) (float64, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return 0, fmt.Errorf("response is not valid JSON: %v", err)
+	}
+	v, err := evalJSONPath(doc, s.path)
+	if err != nil {
+		return 0, err
+	}
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%s is %s, not a number", s.path, jsonKind(v))
+}
+
+// violation returns the first index whose value breaks the order, or -1.
+func (s *sequenceAssertion) violation(values []float64) int {
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1] || s.strict && values[i] == values[i-1] {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *sequenceAssertion) order() string {
+	if s.strict {
+		return "increasing"
+	}
+	return "non-decreasing"
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---- Server-Timing ----
+
+// ServerTiming is a metric from the response's Server-Timing header.
+type ServerTiming struct {
+	Name        string   `json:"name"`
+	DurationMs  *float64 `json:"durationMs,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// parseServerTimings parses the Server-Timing headers of resp, e.g.
+// `db;dur=53.2, app;desc="Render";dur=47`. Malformed metrics are skipped,
+// and malformed parameters ignored, rather than failing the probe.
+func parseServerTimings(header http.Header) []ServerTiming {
+	var timings []ServerTiming
+	for _, value := range header.Values("Server-Timing") {
+		for _, entry := range splitQuoted(value, ',') {
+			params := splitQuoted(entry, ';')
+			name := strings.TrimSpace(params[0])
+			if name == "" || strings.ContainsAny(name, "\"= \t") {
+				continue
+			}
+			timing := ServerTiming{Name: name}
+			for _, param := range params[1:] {
+				key, value, _ := strings.Cut(param, "=")
+				value = strings.TrimSpace(value)
+				if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+					value = unquoted
+				}
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "dur":
+					// Only the first dur counts, as the spec requires
+					if d, err := strconv.ParseFloat(value, 64); err == nil && timing.DurationMs == nil {
+						timing.DurationMs = &d
+					}
+				case "desc":
+					if timing.Description == "" {
+						timing.Description = value
+					}
+				}
+			}
+			timings = append(timings, timing)
+		}
+	}
+	return timings
+}
+
+// splitQuoted splits s at sep, except inside double-quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// serverTimingAssertion maps metric names to the longest duration allowed
+// for them, from 'serverTimingAssertion'.
+type serverTimingAssertion map[string]time.Duration
+
+func parseServerTimingAssertion(config map[string]string) (serverTimingAssertion, error) {
+	raw, ok := config["serverTimingAssertion"]
+	if !ok {
+		return nil, nil
+	}
+	var thresholds map[string]string
+	if err := json.Unmarshal([]byte(raw), &thresholds); err != nil {
+		return nil, fmt.Errorf("invalid 'serverTimingAssertion' in config: %w", err)
+	}
+	if len(thresholds) == 0 {
+		return nil, fmt.Errorf("invalid 'serverTimingAssertion' in config: expected at least one metric")
+	}
+	assertion := make(serverTimingAssertion, len(thresholds))
+	for name, raw := range thresholds {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid 'serverTimingAssertion' in config: metric %s: %q is not a positive duration", name, raw)
+		}
+		assertion[name] = d
+	}
+	return assertion, nil
+}
+
+// criterion checks that every metric is reported with a duration within
+// its threshold. A metric that is missing or has no duration fails.
+func (a serverTimingAssertion) criterion(timings []ServerTiming) criterion {
+	cr := criterion{name: "serverTiming"}
+	found := make(map[string]ServerTiming, len(timings))
+	for _, t := range timings {
+		if _, ok := found[t.Name]; !ok {
+			found[t.Name] = t
+		}
+	}
+
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failures, passes []string
+	for _, name := range names {
+		max := milliseconds(a[name])
+		t, ok := found[name]
+		switch {
+		case !ok:
+			failures = append(failures, fmt.Sprintf("metric %s is not reported", name))
+		case t.DurationMs == nil:
+			failures = append(failures, fmt.Sprintf("metric %s has no duration", name))
+		case *t.DurationMs > max:
+			failures = append(failures, fmt.Sprintf("%s %gms > %gms", name, *t.DurationMs, max))
+		default:
+			passes = append(passes, fmt.Sprintf("%s %gms <= %gms", name, *t.DurationMs, max))
+		}
+	}
+	if len(failures) > 0 {
+		cr.detail = strings.Join(failures, "; ")
+		return cr
+	}
+	cr.passed = true
+	cr.detail = strings.Join(passes, "; ")
+	return cr
+}
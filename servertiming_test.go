@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseServerTimings(t *testing.T) {
+	ms := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name   string
+		values []string
+		want   []ServerTiming
+	}{
+		{name: "none", values: nil, want: nil},
+		{name: "metrics", values: []string{`db;dur=53.2, app;desc="Render; page";dur=47`, "cache;desc=hit"}, want: []ServerTiming{
+			{Name: "db", DurationMs: ms(53.2)},
+			{Name: "app", DurationMs: ms(47), Description: "Render; page"},
+			{Name: "cache", Description: "hit"},
+		}},
+		{name: "first dur wins", values: []string{"db;dur=1;dur=2"}, want: []ServerTiming{{Name: "db", DurationMs: ms(1)}}},
+		{name: "malformed", values: []string{`;dur=5, "quoted", db;dur=fast, , total;dur=12`}, want: []ServerTiming{
+			{Name: "db"},
+			{Name: "total", DurationMs: ms(12)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, v := range tt.values {
+				header.Add("Server-Timing", v)
+			}
+			if got := parseServerTimings(header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseServerTimings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerTimingAssertion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server-Timing", `db;dur=53.2, app;dur=120;desc="Render", miss`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		assertion string
+		wantPass  bool
+		want      string
+	}{
+		{name: "within thresholds", assertion: `{"db": "100ms", "app": "150ms"}`, wantPass: true, want: "[PASS] serverTiming: app 120ms <= 150ms; db 53.2ms <= 100ms"},
+		{name: "over threshold", assertion: `{"db": "50ms"}`, want: "db 53.2ms > 50ms"},
+		{name: "missing metric", assertion: `{"queue": "1s"}`, want: "metric queue is not reported"},
+		{name: "no duration", assertion: `{"miss": "1s"}`, want: "metric miss has no duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "serverTimingAssertion": tt.assertion})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantPass || !strings.Contains(output.Message, tt.want) {
+				t.Errorf("Expected success=%v with %q, got %+v", tt.wantPass, tt.want, output)
+			}
+			if len(output.ServerTimings) != 3 || output.ServerTimings[1].Description != "Render" {
+				t.Errorf("ServerTimings = %+v", output.ServerTimings)
+			}
+		})
+	}
+
+	for _, raw := range []string{`{}`, `{"db": "fast"}`, `{"db": "-1ms"}`, `["db"]`} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "serverTimingAssertion": raw}); err == nil {
+			t.Errorf("Expected error for serverTimingAssertion %s", raw)
+		}
+	}
+}
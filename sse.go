@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ---- Server-Sent Events ----
+
+// defaultMaxSSELineBytes bounds a line of an event stream when
+// 'maxBodyBytes' is not set.
+const defaultMaxSSELineBytes = 16 << 20
+
+// sseLineLimit returns the longest event stream line read for size, the
+// 'maxBodyBytes' check: a data line longer than its bound fails the check
+// anyway. It is never below bufio's default.
+func sseLineLimit(size *bodySize) int {
+	if size == nil || size.max < 0 {
+		return defaultMaxSSELineBytes
+	}
+	// Room for the "data: " field name and the line ending
+	return max(size.max+len("data: ")+2, bufio.MaxScanTokenSize)
+}
+
+// readFirstSSEEvent reads an event stream until its first event carrying
+// data and returns that data, with multiple data lines joined by "\n".
+// Comments and the event, id and retry fields are skipped. Lines longer
+// than maxLine bytes end the read with an error.
+func readFirstSSEEvent(r io.Reader, maxLine int) ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLine)
+	var data []string
+	var seen bool
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line dispatches the event
+			if seen {
+				return []byte(strings.Join(data, "\n")), nil
+			}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		if field == "data" {
+			data = append(data, strings.TrimPrefix(value, " "))
+			seen = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("event stream line longer than %d bytes", maxLine)
+		}
+		return []byte(strings.Join(data, "\n")), err
+	}
+	return nil, errors.New("event stream ended before the first data event")
+}
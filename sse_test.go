@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadFirstSSEEvent(t *testing.T) {
+	tests := []struct {
+		stream  string
+		want    string
+		wantErr bool
+	}{
+		{stream: "data: hello\n\n", want: "hello"},
+		{stream: ": keep-alive\n\nevent: status\nid: 7\ndata: {\"ok\":true}\n\ndata: second\n\n", want: `{"ok":true}`},
+		{stream: "data: line one\ndata:line two\n\n", want: "line one\nline two"},
+		{stream: "event: ping\n\ndata: after ping\n\n", want: "after ping"},
+		{stream: "data: incomplete\n", wantErr: true},
+		{stream: "", wantErr: true},
+		{stream: "data: " + strings.Repeat("x", 100000) + "\n\n", want: strings.Repeat("x", 100000)},
+		{stream: "data: " + strings.Repeat("x", defaultMaxSSELineBytes) + "\n\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := readFirstSSEEvent(strings.NewReader(tt.stream), defaultMaxSSELineBytes)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("readFirstSSEEvent(%q) = %q, want error", tt.stream, got)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("readFirstSSEEvent(%q) = %q, %v, want %q", tt.stream, got, err, tt.want)
+		}
+	}
+}
+
+func TestSSEProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		if r.URL.Path == "/silent" {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		// The stream never ends on its own; the probe must disconnect
+		for i := 0; ; i++ {
+			fmt.Fprintf(w, ": tick\n\nevent: status\ndata: {\"seq\": %d, \"status\": \"ready\"}\n\n", i)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		config         map[string]string
+		wantSuccess    bool
+		wantFirstEvent string
+		wantMessage    string
+	}{
+		{name: "first event", config: map[string]string{}, wantSuccess: true, wantFirstEvent: `{"seq": 0, "status": "ready"}`},
+		{name: "bodyMatch passes", config: map[string]string{"bodyMatch": `"status": "ready"`}, wantSuccess: true, wantMessage: "[PASS] bodyMatch"},
+		{name: "bodyMatch fails", config: map[string]string{"bodyMatch": `"seq": 1\b`}, wantMessage: "[FAIL] bodyMatch"},
+		{name: "no event before timeout", path: "/silent", config: map[string]string{"timeout": "100ms"}, wantMessage: "Body read error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET", "sse": "true"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess || output.FirstEvent != tt.wantFirstEvent && tt.wantFirstEvent != "" {
+				t.Errorf("got success=%v firstEvent=%q, want %v/%q (message: %s)", output.Success, output.FirstEvent, tt.wantSuccess, tt.wantFirstEvent, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantMessage)
+			}
+		})
+	}
+
+	for _, config := range []map[string]string{
+		{"sse": "true", "samples": "3"},
+		{"sse": "true", "warmupRequests": "1"},
+		{"sse": "true", "method": "HEAD"},
+		{"sse": "maybe"},
+		{"bodyMatch": "("},
+	} {
+		config["uri"] = server.URL
+		if config["method"] == "" {
+			config["method"] = "GET"
+		}
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
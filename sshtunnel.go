@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ---- SSH Tunnel ----
+
+// sshTunnel is the 'sshTunnel' setting: connections are made from the jump
+// host instead of from the plugin.
+type sshTunnel struct {
+	Host    string `json:"host"`
+	User    string `json:"user"`
+	KeyPath string `json:"keyPath"`
+	// Target, when set, is dialled through the tunnel instead of the
+	// address of the uri, e.g. an internal service the uri only names.
+	Target string `json:"target"`
+	// KnownHosts verifies the jump host's key. Without it
+	// InsecureIgnoreHostKey must be set explicitly.
+	KnownHosts            string `json:"knownHosts"`
+	InsecureIgnoreHostKey bool   `json:"insecureIgnoreHostKey"`
+
+	config *ssh.ClientConfig
+}
+
+func parseSSHTunnel(config map[string]string) (*sshTunnel, error) {
+	raw, ok := config["sshTunnel"]
+	if !ok {
+		return nil, nil
+	}
+	var tunnel sshTunnel
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&tunnel); err != nil {
+		return nil, fmt.Errorf("invalid 'sshTunnel' in config: %w", err)
+	}
+	if tunnel.Host == "" || tunnel.User == "" || tunnel.KeyPath == "" {
+		return nil, fmt.Errorf("invalid 'sshTunnel' in config: host, user and keyPath are required")
+	}
+	if _, _, err := net.SplitHostPort(tunnel.Host); err != nil {
+		tunnel.Host = net.JoinHostPort(tunnel.Host, "22")
+	}
+
+	key, err := os.ReadFile(tunnel.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'sshTunnel' in config: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'sshTunnel' in config: key %s: %w", tunnel.KeyPath, err)
+	}
+
+	var hostKey ssh.HostKeyCallback
+	switch {
+	case tunnel.KnownHosts != "":
+		if hostKey, err = knownhosts.New(tunnel.KnownHosts); err != nil {
+			return nil, fmt.Errorf("invalid 'sshTunnel' in config: %w", err)
+		}
+	case tunnel.InsecureIgnoreHostKey:
+		hostKey = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("invalid 'sshTunnel' in config: knownHosts is required unless insecureIgnoreHostKey is set")
+	}
+
+	tunnel.config = &ssh.ClientConfig{
+		User:            tunnel.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKey,
+	}
+	return &tunnel, nil
+}
+
+// dial opens a connection to addr, or to the configured target, from the
+// jump host. Each connection gets its own SSH session, which is closed with
+// it; the transport pools the connections themselves.
+func (t *sshTunnel) dial(ctx context.Context, dialer *net.Dialer, addr string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", t.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH host %s: %w", t.Host, err)
+	}
+	// The handshake has no context of its own, so bound it by ctx
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, t.Host, t.config)
+	if !stop() {
+		if err == nil {
+			clientConn.Close()
+		}
+		return nil, fmt.Errorf("SSH handshake with %s interrupted: %w", t.Host, ctx.Err())
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to establish SSH tunnel via %s: %w", t.Host, err)
+	}
+	client := ssh.NewClient(clientConn, chans, reqs)
+
+	if t.Target != "" {
+		addr = t.Target
+	}
+	tunnelled, err := client.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("SSH host %s failed to connect to %s: %w", t.Host, addr, err)
+	}
+	return &sshConn{Conn: tunnelled, client: client}, nil
+}
+
+// sshConn closes its SSH client along with the tunnelled connection.
+type sshConn struct {
+	net.Conn
+	client *ssh.Client
+}
+
+func (c *sshConn) Close() error {
+	err := c.Conn.Close()
+	c.client.Close()
+	return err
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSSHServer runs a jump host that accepts authorized and forwards
+// direct-tcpip channels. It returns the address and host key, and counts
+// the forwarded channels in forwarded.
+func startSSHServer(t *testing.T, authorized ssh.PublicKey, forwarded *atomic.Int32) (string, ssh.PublicKey) {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					var target struct {
+						Host     string
+						Port     uint32
+						OrigHost string
+						OrigPort uint32
+					}
+					if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &target) != nil {
+						newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+						continue
+					}
+					upstream, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.FormatUint(uint64(target.Port), 10)))
+					if err != nil {
+						newChannel.Reject(ssh.ConnectionFailed, err.Error())
+						continue
+					}
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						upstream.Close()
+						continue
+					}
+					forwarded.Add(1)
+					go ssh.DiscardRequests(requests)
+					go func() {
+						io.Copy(channel, upstream)
+						channel.Close()
+					}()
+					go func() {
+						io.Copy(upstream, channel)
+						upstream.Close()
+					}()
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), hostSigner.PublicKey()
+}
+
+// writeSSHKey writes a new private key in OpenSSH format and returns its
+// path and public key.
+func writeSSHKey(t *testing.T, dir, name string) (string, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, sshPub
+}
+
+func TestSSHTunnel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal " + r.Host))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	keyPath, pub := writeSSHKey(t, dir, "id_ed25519")
+	otherKey, _ := writeSSHKey(t, dir, "other")
+	var forwarded atomic.Int32
+	sshAddr, hostKey := startSSHServer(t, pub, &forwarded)
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{sshAddr}, hostKey)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, wrongHostKey := writeSSHKey(t, dir, "wrong_host")
+	wrongKnownHosts := filepath.Join(dir, "wrong_known_hosts")
+	if err := os.WriteFile(wrongKnownHosts, []byte(knownhosts.Line([]string{sshAddr}, wrongHostKey)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tunnel := func(fields map[string]interface{}) string {
+		v := map[string]interface{}{"host": sshAddr, "user": "probe", "keyPath": keyPath, "knownHosts": knownHosts}
+		for k, f := range fields {
+			if f == nil {
+				delete(v, k)
+			} else {
+				v[k] = f
+			}
+		}
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "sshTunnel": tunnel(nil)})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected the request to go through the tunnel, got %v %+v", err, output)
+	}
+	if forwarded.Load() != 1 {
+		t.Errorf("Jump host forwarded %d connections, want 1", forwarded.Load())
+	}
+
+	// The uri only names the service; the target is reached from the jump host
+	output, err = runPlugin(t, map[string]string{"uri": "http://orders.internal.test/health", "method": "GET", "sshTunnel": tunnel(map[string]interface{}{"target": server.Listener.Addr().String()})})
+	if err != nil || !output.Success || !strings.Contains(output.Message, "internal orders.internal.test") {
+		t.Errorf("Expected the target to be reached through the tunnel, got %v %+v", err, output)
+	}
+
+	failures := []struct {
+		name   string
+		tunnel string
+		want   string
+	}{
+		{name: "unauthorized key", tunnel: tunnel(map[string]interface{}{"keyPath": otherKey}), want: "failed to establish SSH tunnel via " + sshAddr},
+		{name: "unknown host key", tunnel: tunnel(map[string]interface{}{"knownHosts": wrongKnownHosts}), want: "failed to establish SSH tunnel"},
+		{name: "unreachable host", tunnel: tunnel(map[string]interface{}{"host": "127.0.0.1:1", "insecureIgnoreHostKey": true, "knownHosts": nil}), want: "failed to connect to SSH host 127.0.0.1:1"},
+		{name: "unreachable target", tunnel: tunnel(map[string]interface{}{"target": "127.0.0.1:1"}), want: "failed to connect to 127.0.0.1:1"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "sshTunnel": tt.tunnel})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success || !strings.Contains(output.Message, tt.want) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.want)
+			}
+		})
+	}
+
+	for _, raw := range []string{
+		tunnel(map[string]interface{}{"user": nil}),
+		tunnel(map[string]interface{}{"knownHosts": nil}),
+		tunnel(map[string]interface{}{"keyPath": filepath.Join(dir, "missing")}),
+		tunnel(map[string]interface{}{"keyPath": knownHosts}),
+		tunnel(map[string]interface{}{"port": 22}),
+	} {
+		if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "sshTunnel": raw}); err == nil {
+			t.Errorf("Expected error for sshTunnel %s", raw)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ---- Start Delay ----
+
+// startDelay is the pause before the probe: 'startDelay' plus a random
+// share of 'startJitter', so steps fired together do not hit the endpoint
+// in one burst.
+type startDelay struct {
+	base, jitter time.Duration
+}
+
+func parseStartDelay(config map[string]string) (startDelay, error) {
+	var d startDelay
+	var err error
+	for key, value := range map[string]*time.Duration{"startDelay": &d.base, "startJitter": &d.jitter} {
+		if *value, err = configDuration(config, key, 0); err != nil {
+			return startDelay{}, err
+		}
+		if *value < 0 {
+			return startDelay{}, fmt.Errorf("invalid '%s' in config: must not be negative", key)
+		}
+	}
+	return d, nil
+}
+
+// wait sleeps for the delay, returning early with the context error when
+// ctx is done first.
+func (d startDelay) wait(ctx context.Context) error {
+	wait := d.base
+	if d.jitter > 0 {
+		wait += rand.N(d.jitter)
+	}
+	if wait == 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
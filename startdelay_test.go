@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	start := time.Now()
+	output, err := runPlugin(t, map[string]string{
+		"uri":         server.URL,
+		"method":      "GET",
+		"outputMode":  "summary",
+		"startDelay":  "200ms",
+		"startJitter": "50ms",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Run returned after %s, before the start delay", elapsed)
+	}
+	// The delay is not part of the reported request duration
+	_, reported, _ := strings.Cut(output.Message, "Duration: ")
+	if d, err := time.ParseDuration(reported); !output.Success || err != nil || d >= 200*time.Millisecond {
+		t.Errorf("Message = %q, want a duration without the delay", output.Message)
+	}
+
+	for _, config := range []map[string]string{
+		{"startDelay": "-1s"},
+		{"startJitter": "soon"},
+	} {
+		config["uri"], config["method"] = server.URL, "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestStartDelayCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := startDelay{base: time.Minute}.wait(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("wait = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// ---- Stdin Mode ----
+
+// runStdin reads a PluginInput JSON document from r, runs it through
+// HTTPPlugin.Run exactly as Argo Rollouts would, and writes the
+// PluginOutput JSON to w. Warnings go to logger, so they stay out of the
+// JSON. It reports whether the probe passed; invalid input or
+// configuration is returned as an error.
+func runStdin(ctx context.Context, logger hclog.Logger, r io.Reader, w io.Writer) (bool, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+	raw, err := (&HTTPPlugin{Logger: logger}).Run(ctx, input)
+	if err != nil {
+		return false, err
+	}
+
+	var output PluginOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return false, err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return false, err
+	}
+	indented.WriteByte('\n')
+	if _, err := indented.WriteTo(w); err != nil {
+		return false, err
+	}
+	return output.Success, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestRunStdin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	success, err := runStdin(context.Background(), hclog.NewNullLogger(), strings.NewReader(`{"config": {"uri": "`+server.URL+`", "method": "GET", "bodyMatch": "hello"}}`), &out)
+	if err != nil || !success {
+		t.Fatalf("Expected success, got %v %v:\n%s", success, err, out.String())
+	}
+	var output PluginOutput
+	if err := json.Unmarshal(out.Bytes(), &output); err != nil {
+		t.Fatalf("Output is not a PluginOutput: %v\n%s", err, out.String())
+	}
+	if !output.Success || !strings.Contains(output.Message, "hello") {
+		t.Errorf("Unexpected output %+v", output)
+	}
+
+	out.Reset()
+	success, err = runStdin(context.Background(), hclog.NewNullLogger(), strings.NewReader(`{"config": {"uri": "`+server.URL+`/missing", "method": "GET"}}`), &out)
+	if err != nil || success || !strings.Contains(out.String(), `"success": false`) {
+		t.Errorf("Expected a failed probe to be printed, got %v %v:\n%s", success, err, out.String())
+	}
+
+	for _, input := range []string{`not json`, `{"config": {"uri": "` + server.URL + `", "method": "GET", "outputMode": "loud"}}`} {
+		out.Reset()
+		if _, err := runStdin(context.Background(), hclog.NewNullLogger(), strings.NewReader(input), &out); err == nil || out.Len() > 0 {
+			t.Errorf("Expected an error and no output for %s, got %v %q", input, err, out.String())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+)
+
+// ---- TLS Settings ----
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// buildTLSConfig returns the client TLS settings described by config. Note
+// that Go does not allow restricting TLS 1.3 cipher suites, so
+// 'tlsCipherSuites' only affects TLS 1.2 and earlier handshakes.
+func buildTLSConfig(config map[string]string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if v := config["tlsMinVersion"]; v != "" {
+		version, ok := tlsVersions[v]
+		if !ok {
+			return nil, fmt.Errorf("invalid 'tlsMinVersion' %q: expected 1.0, 1.1, 1.2 or 1.3", v)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	// Overriding the server name keeps certificate validation against the
+	// expected hostname when the uri targets a pod or node IP directly.
+	// 'hostHeader' names the same virtual host, so it is the default.
+	tlsConfig.ServerName = config["tlsServerName"]
+	if tlsConfig.ServerName == "" && config["hostHeader"] != "" {
+		host := config["hostHeader"]
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		tlsConfig.ServerName = host
+	}
+
+	if names := configList(config, "tlsCipherSuites"); len(names) > 0 {
+		known := make(map[string]uint16)
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			known[suite.Name] = suite.ID
+		}
+		for _, name := range names {
+			id, ok := known[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid 'tlsCipherSuites': unknown cipher suite %q", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildTLSConfig(t *testing.T) {
+	tlsConfig, err := buildTLSConfig(map[string]string{
+		"tlsMinVersion":   "1.2",
+		"tlsCipherSuites": "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want %x", tlsConfig.MinVersion, tls.VersionTLS12)
+	}
+	want := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}
+	if len(tlsConfig.CipherSuites) != len(want) || tlsConfig.CipherSuites[0] != want[0] || tlsConfig.CipherSuites[1] != want[1] {
+		t.Errorf("CipherSuites = %v, want %v", tlsConfig.CipherSuites, want)
+	}
+
+	for _, config := range []map[string]string{
+		{"tlsMinVersion": "1.4"},
+		{"tlsMinVersion": "TLS1.2"},
+		{"tlsCipherSuites": "TLS_NOT_A_SUITE"},
+	} {
+		if _, err := buildTLSConfig(config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
+
+func TestTLSMinVersionEnforced(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "tlsMinVersion": "1.3"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Success {
+		t.Error("Expected handshake failure against a TLS 1.2 only server")
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	sni := make(chan string, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			sni <- hello.ServerName
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	if _, err := runPlugin(t, map[string]string{"uri": server.URL, "method": "GET", "tlsServerName": "canary.example.com"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := <-sni; got != "canary.example.com" {
+		t.Errorf("SNI = %q, want canary.example.com", got)
+	}
+
+	if _, err := runPlugin(t, map[string]string{"uri": "http://127.0.0.1", "method": "GET", "tlsServerName": "canary.example.com"}); err == nil {
+		t.Error("Expected error when tlsServerName is used with an http uri")
+	}
+}
+
+func TestTLSServerNameFromHostHeader(t *testing.T) {
+	for _, tt := range []struct {
+		config map[string]string
+		want   string
+	}{
+		{config: map[string]string{"hostHeader": "canary.example.com:8443"}, want: "canary.example.com"},
+		{config: map[string]string{"hostHeader": "canary.example.com", "tlsServerName": "sni.example.com"}, want: "sni.example.com"},
+		{config: map[string]string{}, want: ""},
+	} {
+		tlsConfig, err := buildTLSConfig(tt.config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tlsConfig.ServerName != tt.want {
+			t.Errorf("config %v: ServerName = %q, want %q", tt.config, tlsConfig.ServerName, tt.want)
+		}
+	}
+}
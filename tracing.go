@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// ---- Tracing ----
+
+// tracingEnv enables OpenTelemetry tracing when set to a true value. The
+// exporter is configured through the standard OTEL_EXPORTER_OTLP_* variables.
+const tracingEnv = "PLUGIN_OTEL_ENABLED"
+
+// tracer creates the plugin's spans. It is a no-op unless tracing is
+// enabled, and Run skips span bookkeeping entirely in that case.
+var (
+	tracer         trace.Tracer = noop.NewTracerProvider().Tracer("")
+	tracingEnabled bool
+)
+
+// setupTracing installs an OTLP/HTTP exporting tracer when tracingEnv is
+// set. The returned function flushes and stops the exporter.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	raw := os.Getenv(tracingEnv)
+	if raw == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", tracingEnv, raw, err)
+	}
+	if !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", "argo-rollouts-plugin-curl"),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+
+	tracer = provider.Tracer("naviteq.io/argo-rollouts-plugin-curl")
+	tracingEnabled = true
+	return provider.Shutdown, nil
+}
+
+// tracingTransport records a span for every outbound attempt, so retries
+// and warm-ups each appear individually under the run span.
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(requestAttributes(req)...)
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+func requestAttributes(req *http.Request) []attribute.KeyValue {
+	u := *req.URL
+	u.User = nil
+	return []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", u.String()),
+	}
+}
+
+// startRunSpan opens the span covering a whole Run. When tracing is
+// disabled it returns ctx unchanged with a no-op span.
+func startRunSpan(ctx context.Context, method string, target *url.URL) (context.Context, trace.Span) {
+	if !tracingEnabled {
+		return ctx, noop.Span{}
+	}
+	u := *target
+	u.User = nil
+	return tracer.Start(ctx, "curl-plugin.run", trace.WithAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("url.full", u.String()),
+	))
+}
+
+// endRunSpan records the outcome of a Run on its span.
+func endRunSpan(span trace.Span, status int, duration time.Duration, result PluginOutput) {
+	if !tracingEnabled {
+		return
+	}
+	if status != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+	span.SetAttributes(
+		attribute.Int64("probe.duration_ms", duration.Milliseconds()),
+		attribute.Bool("probe.success", result.Success),
+	)
+	if !result.Success {
+		span.SetStatus(codes.Error, result.FailureReason)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingSpans(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevTracer, prevEnabled := tracer, tracingEnabled
+	tracer, tracingEnabled = provider.Tracer("test"), true
+	defer func() { tracer, tracingEnabled = prevTracer, prevEnabled }()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":          server.URL,
+		"method":       "GET",
+		"retries":      "1",
+		"retryBackoff": "1ms",
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !output.Success {
+		t.Fatalf("expected success, got %q", output.Message)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans (run and two attempts), got %d", len(spans))
+	}
+
+	var run tracetest.SpanStub
+	var attemptStatuses []int64
+	for _, span := range spans {
+		if span.Name == "curl-plugin.run" {
+			run = span
+			continue
+		}
+		attrs := spanAttributes(span)
+		if attrs["http.request.method"].AsString() != "GET" || attrs["url.full"].AsString() != server.URL {
+			t.Errorf("attempt span %q has unexpected attributes %v", span.Name, span.Attributes)
+		}
+		attemptStatuses = append(attemptStatuses, attrs["http.response.status_code"].AsInt64())
+	}
+	if run.Name == "" {
+		t.Fatal("missing run span")
+	}
+	if len(attemptStatuses) != 2 || attemptStatuses[0] != 502 || attemptStatuses[1] != 200 {
+		t.Errorf("expected attempt statuses [502 200], got %v", attemptStatuses)
+	}
+	for _, span := range spans {
+		if span.Name != run.Name && span.Parent.SpanID() != run.SpanContext.SpanID() {
+			t.Errorf("attempt span %q is not a child of the run span", span.Name)
+		}
+	}
+
+	attrs := spanAttributes(run)
+	if attrs["http.response.status_code"].AsInt64() != 200 {
+		t.Errorf("expected run status 200, got %v", attrs["http.response.status_code"])
+	}
+	if _, ok := attrs["probe.duration_ms"]; !ok {
+		t.Error("run span is missing probe.duration_ms")
+	}
+	if !attrs["probe.success"].AsBool() {
+		t.Error("expected probe.success to be true")
+	}
+}
+
+func TestTracingDisabledByDefault(t *testing.T) {
+	t.Setenv(tracingEnv, "")
+	shutdown, err := setupTracing(context.Background())
+	if err != nil {
+		t.Fatalf("setupTracing failed: %v", err)
+	}
+	defer shutdown(context.Background())
+	if tracingEnabled {
+		t.Error("expected tracing to stay disabled")
+	}
+
+	t.Setenv(tracingEnv, "maybe")
+	if _, err := setupTracing(context.Background()); err == nil {
+		t.Error("expected an error for an invalid value")
+	}
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"cmp"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ---- Transport ----
+
+// newTransport returns an HTTP transport configured from config, based on
+// the defaults of http.DefaultTransport.
+func newTransport(config map[string]string) (*http.Transport, error) {
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dial, err := newDialContext(config)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.DialContext = dial
+
+	// A non-nil, empty TLSNextProto stops the transport from negotiating
+	// HTTP/2, the documented way of forcing HTTP/1.1. A 'protocolVersion'
+	// pins HTTP/1.x as well.
+	disableHTTP2, err := configBool(config, "disableHttp2", false)
+	if err != nil {
+		return nil, err
+	}
+	if disableHTTP2 || config["protocolVersion"] != "" {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if raw := config["proxyUrl"]; raw != "" {
+		proxy, err := newProxyFunc(raw, config)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxy
+	}
+	if raw, ok := config["proxyRules"]; ok {
+		rules, err := parseProxyRules(raw)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = rules.proxyFunc(transport.Proxy)
+	}
+
+	// Headers for the proxy's CONNECT request, e.g. token-based proxy auth.
+	// They only apply to tunnelled (https) targets when a proxy is in use.
+	if raw, ok := config["proxyHeaders"]; ok {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			return nil, fmt.Errorf("invalid 'proxyHeaders' in config: %w", err)
+		}
+		transport.ProxyConnectHeader = make(http.Header, len(headers))
+		for name, value := range headers {
+			transport.ProxyConnectHeader.Set(name, value)
+		}
+	}
+	return transport, nil
+}
+
+// newProxyFunc routes requests through proxyURL, except for hosts matching
+// 'noProxy' (or NO_PROXY when the key is absent), which are reached
+// directly. Loopback targets are never proxied.
+func newProxyFunc(proxyURL string, config map[string]string) (func(*http.Request) (*url.URL, error), error) {
+	if _, err := parseProxyURL("proxyUrl", proxyURL); err != nil {
+		return nil, err
+	}
+
+	noProxy, ok := config["noProxy"]
+	if !ok {
+		noProxy = httpproxy.FromEnvironment().NoProxy
+	}
+	proxy := (&httpproxy.Config{HTTPProxy: proxyURL, HTTPSProxy: proxyURL, NoProxy: noProxy}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// parseProxyURL validates a proxy URL from the config key key.
+func parseProxyURL(key, raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid '%s' in config: %q is not an absolute URL", key, raw)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid '%s' in config: unsupported scheme %q", key, u.Scheme)
+	}
+	return u, nil
+}
+
+// proxyRule routes hosts matching pattern through proxy, or directly when
+// proxy is nil.
+type proxyRule struct {
+	pattern string
+	proxy   *url.URL
+}
+
+type proxyRules []proxyRule
+
+// parseProxyRules reads 'proxyRules', a JSON object mapping host patterns
+// such as "*.eu.example.com" to proxy URLs, or to "" for a direct
+// connection. Exact hosts take precedence over patterns, and longer
+// patterns over shorter ones.
+func parseProxyRules(raw string) (proxyRules, error) {
+	var table map[string]string
+	if err := json.Unmarshal([]byte(raw), &table); err != nil {
+		return nil, fmt.Errorf("invalid 'proxyRules' in config: %w", err)
+	}
+	rules := make(proxyRules, 0, len(table))
+	for pattern, proxyURL := range table {
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("invalid 'proxyRules' in config: bad host pattern %q", pattern)
+		}
+		rule := proxyRule{pattern: pattern}
+		if proxyURL != "" {
+			u, err := parseProxyURL("proxyRules", proxyURL)
+			if err != nil {
+				return nil, err
+			}
+			rule.proxy = u
+		}
+		rules = append(rules, rule)
+	}
+	slices.SortFunc(rules, func(a, b proxyRule) int {
+		aWild, bWild := strings.ContainsAny(a.pattern, "*?["), strings.ContainsAny(b.pattern, "*?[")
+		if aWild != bWild {
+			if aWild {
+				return 1
+			}
+			return -1
+		}
+		if n := cmp.Compare(len(b.pattern), len(a.pattern)); n != 0 {
+			return n
+		}
+		return strings.Compare(a.pattern, b.pattern)
+	})
+	return rules, nil
+}
+
+// proxyFunc selects the proxy of the first rule matching the request's
+// host. Requests to other hosts use fallback.
+func (r proxyRules) proxyFunc(fallback func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+		for _, rule := range r {
+			if ok, _ := path.Match(rule.pattern, host); ok {
+				return rule.proxy, nil
+			}
+		}
+		if fallback == nil {
+			return nil, nil
+		}
+		return fallback(req)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxyHeaders(t *testing.T) {
+	transport, err := newTransport(map[string]string{
+		"proxyHeaders": `{"proxy-authorization": "Bearer token", "X-Egress-Tenant": "canary"}`,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := transport.ProxyConnectHeader.Get("Proxy-Authorization"); got != "Bearer token" {
+		t.Errorf("Proxy-Authorization = %q, want %q", got, "Bearer token")
+	}
+	if got := transport.ProxyConnectHeader.Get("X-Egress-Tenant"); got != "canary" {
+		t.Errorf("X-Egress-Tenant = %q, want %q", got, "canary")
+	}
+
+	if _, err := newTransport(map[string]string{"proxyHeaders": `["Proxy-Authorization"]`}); err == nil {
+		t.Error("Expected error for non-object proxyHeaders")
+	}
+}
+
+func TestDisableHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		config    map[string]string
+		wantProto string
+	}{
+		{config: map[string]string{}, wantProto: "HTTP/2.0"},
+		{config: map[string]string{"disableHttp2": "true"}, wantProto: "HTTP/1.1"},
+	} {
+		transport, err := newTransport(tt.config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		transport.TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.Proto != tt.wantProto {
+			t.Errorf("config %v: Proto = %s, want %s", tt.config, resp.Proto, tt.wantProto)
+		}
+	}
+
+	if _, err := newTransport(map[string]string{"disableHttp2": "sometimes"}); err == nil {
+		t.Error("Expected error for invalid disableHttp2")
+	}
+}
+
+func TestProxyURLHonorsNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "env.internal")
+	t.Setenv("no_proxy", "")
+
+	tests := []struct {
+		name      string
+		config    map[string]string
+		target    string
+		wantProxy bool
+	}{
+		{name: "proxied", config: map[string]string{}, target: "https://api.example.com/", wantProxy: true},
+		{name: "NO_PROXY from env", config: map[string]string{}, target: "http://env.internal/", wantProxy: false},
+		{name: "noProxy key replaces env", config: map[string]string{"noProxy": ".svc.cluster.local,10.0.0.0/8"}, target: "http://env.internal/", wantProxy: true},
+		{name: "noProxy domain suffix", config: map[string]string{"noProxy": ".svc.cluster.local,10.0.0.0/8"}, target: "http://canary.default.svc.cluster.local:8080/", wantProxy: false},
+		{name: "noProxy CIDR", config: map[string]string{"noProxy": ".svc.cluster.local,10.0.0.0/8"}, target: "http://10.1.2.3/", wantProxy: false},
+		{name: "empty noProxy", config: map[string]string{"noProxy": ""}, target: "http://env.internal/", wantProxy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["proxyUrl"] = "http://proxy.example.com:3128"
+			transport, err := newTransport(tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			proxy, err := transport.Proxy(req)
+			if err != nil {
+				t.Fatalf("Proxy returned error: %v", err)
+			}
+			if got := proxy != nil; got != tt.wantProxy {
+				t.Errorf("proxied = %v (%v), want %v", got, proxy, tt.wantProxy)
+			}
+		})
+	}
+
+	for _, raw := range []string{"proxy.example.com:3128", "ftp://proxy.example.com"} {
+		if _, err := newTransport(map[string]string{"proxyUrl": raw}); err == nil {
+			t.Errorf("Expected error for proxyUrl %q", raw)
+		}
+	}
+}
+
+func TestProxyURLRoutesRequests(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host != "canary.example.com" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("via proxy"))
+	}))
+	defer proxy.Close()
+
+	output, err := runPlugin(t, map[string]string{"uri": "http://canary.example.com/", "method": "GET", "proxyUrl": proxy.URL, "noProxy": ""})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success || !strings.Contains(output.Message, "via proxy") {
+		t.Errorf("Expected the request to go through the proxy, got: %v", output.Message)
+	}
+}
+
+func TestProxyRules(t *testing.T) {
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("via " + name + " to " + r.URL.Host))
+		}))
+	}
+	eu, us, fallback := newProxy("eu"), newProxy("us"), newProxy("fallback")
+	defer eu.Close()
+	defer us.Close()
+	defer fallback.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("direct"))
+	}))
+	defer direct.Close()
+
+	rules := `{"*.eu.example.com": "` + eu.URL + `", "*.example.com": "` + us.URL + `", "legacy.eu.example.com": "` + us.URL + `", "127.0.0.1": ""}`
+	tests := []struct {
+		name   string
+		uri    string
+		config map[string]string
+		want   string
+	}{
+		{name: "pattern", uri: "http://orders.eu.example.com/", want: "via eu to orders.eu.example.com"},
+		{name: "longer pattern wins", uri: "http://orders.us.example.com/", want: "via us to orders.us.example.com"},
+		{name: "exact host wins", uri: "http://legacy.eu.example.com/", want: "via us to legacy.eu.example.com"},
+		{name: "direct", uri: direct.URL, config: map[string]string{"proxyUrl": fallback.URL, "noProxy": ""}, want: "direct"},
+		{name: "unmatched falls back to proxyUrl", uri: "http://orders.example.org/", config: map[string]string{"proxyUrl": fallback.URL, "noProxy": ""}, want: "via fallback to orders.example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": tt.uri, "method": "GET", "proxyRules": rules}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !output.Success || !strings.Contains(output.Message, tt.want) {
+				t.Errorf("Expected %q, got %+v", tt.want, output)
+			}
+		})
+	}
+
+	for _, raw := range []string{`["a"]`, `{"[": "http://proxy"}`, `{"*.example.com": "proxy:3128"}`, `{"*.example.com": "ftp://proxy"}`} {
+		if _, err := newTransport(map[string]string{"proxyRules": raw}); err == nil {
+			t.Errorf("Expected error for proxyRules %s", raw)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ---- Transport Cache ----
+
+// transportKeys are the config keys newTransport reads. Runs that agree on
+// all of them share a transport, and with it a pool of idle connections.
+// NTLM authenticates the connection itself, so its credentials are part of
+// the key: a pooled connection is only reused by the same identity.
+var transportKeys = []string{
+	"tlsMinVersion", "tlsServerName", "tlsCipherSuites", "hostHeader",
+	"connectTimeout", "tcpKeepAlive", "localAddr", "dnsServer", "network", "sshTunnel",
+	"disableHttp2", "protocolVersion", "proxyUrl", "noProxy", "proxyHeaders", "proxyRules",
+	"authScheme", "ntlmUser", "ntlmDomain", "ntlmPassword",
+}
+
+const (
+	// transportIdleTTL is how long a cached transport may go unused before
+	// it is dropped and its connections closed.
+	transportIdleTTL = 5 * time.Minute
+	// maxCachedTransports bounds the cache when configs vary widely; the
+	// least recently used transport is dropped first.
+	maxCachedTransports = 32
+)
+
+// transports is shared by every Run in the process.
+var transports = newTransportCache(transportIdleTTL, maxCachedTransports)
+
+type cachedTransport struct {
+	transport *http.Transport
+	lastUsed  time.Time
+}
+
+// transportCache reuses transports across Run calls, keyed by the config
+// that shaped them.
+type transportCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedTransport
+	idleTTL time.Duration
+	max     int
+	now     func() time.Time
+}
+
+func newTransportCache(idleTTL time.Duration, max int) *transportCache {
+	return &transportCache{
+		entries: make(map[string]*cachedTransport),
+		idleTTL: idleTTL,
+		max:     max,
+		now:     time.Now,
+	}
+}
+
+// transportKey serializes the transport-relevant part of config. Absent
+// and empty keys are told apart, as 'noProxy' treats them differently.
+func transportKey(config map[string]string) string {
+	subset := make(map[string]string)
+	for _, key := range transportKeys {
+		if v, ok := config[key]; ok {
+			subset[key] = v
+		}
+	}
+	// Marshalling a map sorts its keys, so the result is canonical.
+	key, _ := json.Marshal(subset)
+	return string(key)
+}
+
+// get returns the cached transport for config, building one on a miss.
+// Invalid configs are not cached, so they fail the same way every time.
+func (c *transportCache) get(config map[string]string) (*http.Transport, error) {
+	key := transportKey(config)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.evictLocked(now)
+	if entry, ok := c.entries[key]; ok {
+		entry.lastUsed = now
+		return entry.transport, nil
+	}
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.entries) >= c.max {
+		c.evictOldestLocked()
+	}
+	c.entries[key] = &cachedTransport{transport: transport, lastUsed: now}
+	return transport, nil
+}
+
+// evictLocked drops the transports unused for longer than idleTTL. Requests
+// still in flight on them finish; only idle connections are closed.
+func (c *transportCache) evictLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.lastUsed) > c.idleTTL {
+			entry.transport.CloseIdleConnections()
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *transportCache) evictOldestLocked() {
+	var oldest string
+	for key, entry := range c.entries {
+		if oldest == "" || entry.lastUsed.Before(c.entries[oldest].lastUsed) {
+			oldest = key
+		}
+	}
+	if oldest != "" {
+		c.entries[oldest].transport.CloseIdleConnections()
+		delete(c.entries, oldest)
+	}
+}
+
+// len reports the number of cached transports.
+func (c *transportCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportCache(t *testing.T) {
+	cache := newTransportCache(time.Minute, 2)
+	now := time.Unix(0, 0)
+	cache.now = func() time.Time { return now }
+
+	a, err := cache.get(map[string]string{"uri": "http://a", "connectTimeout": "2s"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	same, _ := cache.get(map[string]string{"uri": "http://b", "method": "POST", "connectTimeout": "2s"})
+	if same != a {
+		t.Error("Expected configs differing only in request keys to share a transport")
+	}
+	other, _ := cache.get(map[string]string{"connectTimeout": "3s"})
+	if other == a {
+		t.Error("Expected a different connectTimeout to get its own transport")
+	}
+	empty, _ := cache.get(map[string]string{"connectTimeout": "2s", "noProxy": ""})
+	if empty == a {
+		t.Error("Expected an empty noProxy to differ from an absent one")
+	}
+	if n := cache.len(); n != 2 {
+		t.Errorf("len = %d, want 2 after exceeding the limit", n)
+	}
+
+	// NTLM-authenticated connections are never shared across identities
+	ntlm := map[string]string{"authScheme": "ntlm", "ntlmUser": "probe", "ntlmPassword": "a"}
+	alice, _ := cache.get(ntlm)
+	for key, value := range map[string]string{"ntlmUser": "other", "ntlmDomain": "CORP", "ntlmPassword": "b", "authScheme": ""} {
+		variant := map[string]string{}
+		for k, v := range ntlm {
+			variant[k] = v
+		}
+		variant[key] = value
+		if got, _ := cache.get(variant); got == alice {
+			t.Errorf("Expected a different %s to get its own transport", key)
+		}
+	}
+
+	if _, err := cache.get(map[string]string{"disableHttp2": "sometimes"}); err == nil {
+		t.Error("Expected error for invalid transport config")
+	}
+	if n := cache.len(); n != 2 {
+		t.Errorf("len = %d, want invalid configs not to be cached", n)
+	}
+
+	now = now.Add(2 * time.Minute)
+	fresh, _ := cache.get(map[string]string{"connectTimeout": "2s"})
+	if fresh == a {
+		t.Error("Expected an idle transport to be evicted")
+	}
+	if n := cache.len(); n != 1 {
+		t.Errorf("len = %d, want 1 after eviction", n)
+	}
+}
+
+func TestTransportReusedAcrossRuns(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	config := map[string]string{"uri": server.URL, "method": "GET", "connectTimeout": "7s"}
+	for i := 0; i < 3; i++ {
+		output, err := runPlugin(t, config)
+		if err != nil || !output.Success {
+			t.Fatalf("Run %d failed: %v %+v", i, err, output)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("Server saw %d connections, want 1 reused across runs", n)
+	}
+}
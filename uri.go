@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ---- URI Validation ----
+
+// parseTargetURI validates raw as an absolute http(s) URL. When raw has no
+// scheme and defaultScheme is set, the scheme is prepended before parsing.
+func parseTargetURI(raw, defaultScheme string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		if defaultScheme == "" {
+			return nil, fmt.Errorf("invalid 'uri' %q: missing scheme (expected http:// or https://)", raw)
+		}
+		if defaultScheme != "http" && defaultScheme != "https" {
+			return nil, fmt.Errorf("invalid 'defaultScheme' %q: expected http or https", defaultScheme)
+		}
+		raw = defaultScheme + "://" + raw
+	}
+
+	u, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'uri' %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid 'uri' %q: unsupported scheme %q (expected http or https)", raw, u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid 'uri' %q: missing host", raw)
+	}
+	return u, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestParseTargetURI(t *testing.T) {
+	tests := []struct {
+		raw           string
+		defaultScheme string
+		want          string
+		wantErr       bool
+	}{
+		{raw: "https://example.com/health", want: "https://example.com/health"},
+		{raw: " http://example.com:8080 ", want: "http://example.com:8080"},
+		{raw: "example.com/health", wantErr: true},
+		{raw: "example.com:8080", wantErr: true},
+		{raw: "example.com/health", defaultScheme: "https", want: "https://example.com/health"},
+		{raw: "example.com", defaultScheme: "ftp", wantErr: true},
+		{raw: "ftp://example.com", wantErr: true},
+		{raw: "http:///path", wantErr: true},
+		{raw: "http://exa mple.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		u, err := parseTargetURI(tt.raw, tt.defaultScheme)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTargetURI(%q, %q) = %v, want error", tt.raw, tt.defaultScheme, u)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTargetURI(%q, %q) unexpected error: %v", tt.raw, tt.defaultScheme, err)
+			continue
+		}
+		if u.String() != tt.want {
+			t.Errorf("parseTargetURI(%q, %q) = %q, want %q", tt.raw, tt.defaultScheme, u.String(), tt.want)
+		}
+	}
+}
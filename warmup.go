@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// ---- Warm-up Requests ----
+
+// warmUp sends count copies of req and discards their results, so cold
+// endpoints are initialised before the evaluated request. Only context
+// cancellation is reported; individual warm-up failures are ignored.
+func warmUp(ctx context.Context, client *http.Client, req *http.Request, count int) error {
+	for i := 0; i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		warm, err := cloneRequest(ctx, req)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(warm)
+		if err != nil {
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	return ctx.Err()
+}
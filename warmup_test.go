@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWarmupRequests(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The endpoint fails until it has been warmed up twice
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	output, err := runPlugin(t, map[string]string{
+		"uri":            server.URL,
+		"method":         "POST",
+		"body":           "payload",
+		"warmupRequests": "2",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !output.Success {
+		t.Errorf("Expected warm-ups to be ignored, got: %v", output.Message)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("server hits = %d, want 3", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+)
+
+// ---- Weighted Targets ----
+
+// weightedURI is one entry of the 'weightedUris' list.
+type weightedURI struct {
+	URI    string `json:"uri"`
+	Weight int    `json:"weight"`
+}
+
+// maxTotalWeight bounds the sum of the weights, which pickWeighted draws
+// from, so that it cannot overflow.
+const maxTotalWeight = 1_000_000
+
+func parseWeightedURIs(raw string) ([]weightedURI, error) {
+	var targets []weightedURI
+	if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+		return nil, fmt.Errorf("invalid 'weightedUris' in config: %w", err)
+	}
+	total := 0
+	for i, target := range targets {
+		if target.URI == "" {
+			return nil, fmt.Errorf("invalid 'weightedUris' in config: entry %d has no uri", i)
+		}
+		if target.Weight < 0 {
+			return nil, fmt.Errorf("invalid 'weightedUris' in config: entry %d has a negative weight", i)
+		}
+		if target.Weight > maxTotalWeight-total {
+			return nil, fmt.Errorf("invalid 'weightedUris' in config: weights must add up to at most %d", maxTotalWeight)
+		}
+		total += target.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("invalid 'weightedUris' in config: weights must add up to more than 0")
+	}
+	return targets, nil
+}
+
+// pickWeighted chooses a target with probability proportional to its weight.
+func pickWeighted(targets []weightedURI) string {
+	total := 0
+	for _, target := range targets {
+		total += target.Weight
+	}
+	n := rand.IntN(total)
+	for _, target := range targets {
+		if n < target.Weight {
+			return target.URI
+		}
+		n -= target.Weight
+	}
+	panic("unreachable")
+}
+
+// runWeighted probes one target picked at random from 'weightedUris', so
+// repeated runs spread across the backends.
+func (p *HTTPPlugin) runWeighted(ctx context.Context, config map[string]string) (PluginOutput, error) {
+	if config["uri"] != "" {
+		return PluginOutput{}, fmt.Errorf("'weightedUris' cannot be combined with 'uri'")
+	}
+	targets, err := parseWeightedURIs(config["weightedUris"])
+	if err != nil {
+		return PluginOutput{}, err
+	}
+
+	probe := make(map[string]string, len(config))
+	for key, value := range config {
+		if key != "weightedUris" {
+			probe[key] = value
+		}
+	}
+	probe["uri"] = pickWeighted(targets)
+
+	output, err := p.runEntry(ctx, probe)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	output.Target = probe["uri"]
+	return output, nil
+}
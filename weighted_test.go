@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPickWeighted(t *testing.T) {
+	targets := []weightedURI{{URI: "a", Weight: 3}, {URI: "b", Weight: 1}, {URI: "c", Weight: 0}}
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[pickWeighted(targets)]++
+	}
+	if counts["c"] != 0 {
+		t.Errorf("zero-weight target picked %d times", counts["c"])
+	}
+	// Expect about 3000/1000; the bounds are far outside random variation
+	if counts["a"] < 2700 || counts["a"] > 3300 {
+		t.Errorf("counts = %v, want about 3:1", counts)
+	}
+}
+
+func TestWeightedURIs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/b" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		output, err := runPlugin(t, map[string]string{
+			"method":       "GET",
+			"weightedUris": `[{"uri": "` + server.URL + `/a", "weight": 1}, {"uri": "` + server.URL + `/b", "weight": 1}]`,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := output.Target == server.URL+"/a"; output.Success != want {
+			t.Errorf("target %q: Success = %v", output.Target, output.Success)
+		}
+		seen[output.Target] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected both targets to be picked, got %v", seen)
+	}
+
+	for _, config := range []map[string]string{
+		{"weightedUris": `{"uri": "http://a"}`},
+		{"weightedUris": `[]`},
+		{"weightedUris": `[{"uri": "http://a", "weight": 0}]`},
+		{"weightedUris": `[{"uri": "http://a", "weight": -1}, {"uri": "http://b", "weight": 2}]`},
+		{"weightedUris": `[{"uri": "http://a", "weight": 9223372036854775807}, {"uri": "http://b", "weight": 1}]`},
+		{"weightedUris": `[{"uri": "http://a", "weight": 600000}, {"uri": "http://b", "weight": 600000}]`},
+		{"weightedUris": `[{"weight": 1}]`},
+		{"weightedUris": `[{"uri": "http://a", "weight": 1}]`, "uri": "http://b"},
+	} {
+		config["method"] = "GET"
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// ---- XPath ----
+
+// xmlPathExpr is a compiled 'xmlPath' XPath expression.
+type xmlPathExpr struct {
+	expr string
+	path *xpath.Expr
+}
+
+func compileXMLPath(expr string) (*xmlPathExpr, error) {
+	path, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'xmlPath' expression: %w", err)
+	}
+	return &xmlPathExpr{expr: expr, path: path}, nil
+}
+
+// criterion evaluates the expression against the XML body and matches the
+// result against expected, like 'jsonPath'. A node set yields the text of
+// each node, one per line; an empty one fails.
+func (x *xmlPathExpr) criterion(expected *valueMatcher, body []byte) criterion {
+	cr := criterion{name: "xmlPath " + x.expr}
+
+	doc, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		cr.detail = fmt.Sprintf("response is not valid XML: %v", err)
+		return cr
+	}
+	if xmlquery.FindOne(doc, "/*") == nil {
+		cr.detail = "response is not valid XML: no root element"
+		return cr
+	}
+
+	var actual string
+	switch v := x.path.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		var texts []string
+		for v.MoveNext() {
+			texts = append(texts, strings.TrimSpace(v.Current().Value()))
+		}
+		if len(texts) == 0 {
+			cr.detail = "xmlPath matched no node"
+			return cr
+		}
+		actual = strings.Join(texts, "\n")
+	case float64:
+		actual = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		actual = strconv.FormatBool(v)
+	case string:
+		actual = v
+	default:
+		actual = fmt.Sprint(v)
+	}
+
+	if expected == nil {
+		cr.passed, cr.detail = true, actual
+		return cr
+	}
+	cr.passed = expected.match(actual)
+	if cr.passed {
+		cr.detail = actual
+	} else {
+		cr.detail = fmt.Sprintf("got %q (expected %q)", actual, expected.raw)
+	}
+	return cr
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestXMLPath(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		switch r.URL.Path {
+		case "/text":
+			w.Write([]byte("not xml"))
+		case "/broken":
+			w.Write([]byte("<status><ready>true</status>"))
+		default:
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <health version="2.1">
+      <status>UP</status>
+      <node name="a">ready</node>
+      <node name="b">draining</node>
+    </health>
+  </soap:Body>
+</soap:Envelope>`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		xmlPath     string
+		expected    string
+		wantSuccess bool
+		wantDetail  string
+	}{
+		{name: "element text", xmlPath: "//health/status", expected: "UP", wantSuccess: true, wantDetail: "[PASS] xmlPath //health/status: UP"},
+		{name: "attribute", xmlPath: "//health/@version", expected: "regex:^2\\.", wantSuccess: true},
+		{name: "count", xmlPath: "count(//node)", expected: "2", wantSuccess: true},
+		{name: "predicate", xmlPath: "//node[@name='b']", expected: "ready", wantDetail: `got "draining" (expected "ready")`},
+		{name: "several nodes", xmlPath: "//node", expected: "ready\ndraining", wantSuccess: true},
+		{name: "no expected value", xmlPath: "//status", wantSuccess: true},
+		{name: "unresolved", xmlPath: "//missing", wantDetail: "xmlPath matched no node"},
+		{name: "not xml", path: "/text", xmlPath: "//status", wantDetail: "response is not valid XML"},
+		{name: "malformed xml", path: "/broken", xmlPath: "//status", wantDetail: "response is not valid XML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]string{"uri": server.URL + tt.path, "method": "GET", "xmlPath": tt.xmlPath}
+			if tt.expected != "" {
+				config["expectedValue"] = tt.expected
+			}
+			output, err := runPlugin(t, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (message: %s)", output.Success, tt.wantSuccess, output.Message)
+			}
+			if !strings.Contains(output.Message, tt.wantDetail) {
+				t.Errorf("Message = %q, want it to contain %q", output.Message, tt.wantDetail)
+			}
+		})
+	}
+	if accept != "application/xml" {
+		t.Errorf("Accept = %q, want application/xml", accept)
+	}
+
+	for _, config := range []map[string]string{
+		{"xmlPath": "//status[", "method": "GET"},
+		{"xmlPath": "//status", "method": "HEAD"},
+	} {
+		config["uri"] = server.URL
+		if _, err := runPlugin(t, config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}